@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/natemollica-nm/topn/internal/cleanup"
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+// runApply implements `topn apply [-yes] plan.json`.
+func runApply(args []string) int {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	force := fs.Bool("force", false, "remove files even while a process holds them open")
+	var protect utils.MultiFlag
+	fs.Var(&protect, "protect", "never remove paths matching this glob or under this prefix (repeatable)")
+	auditPath := fs.String("audit-log", defaultAuditPath(), "append a record of every removal to this JSON-lines file")
+	fs.String("config", defaultConfigPath(), "configuration file whose protect list also applies")
+	fs.String("profile", "", "also apply the protect list of this profile from the configuration file")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: topn apply [-yes] plan.json\n\n")
+		fmt.Fprintf(fs.Output(), "Execute a removal plan written by topn -dry-run, skipping files that changed since.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	// The plan may predate a protect setting, and nothing else guards the
+	// paths it names, so the configured protect lists apply here too.
+	sources, err := configSources(fs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, s := range sources {
+		for _, p := range s["protect"] {
+			protect.Set(p)
+		}
+	}
+
+	patterns, err := cleanup.Protect(protect).Abs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	plan, err := cleanup.LoadPlan(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("📋 Plan from %s: %s %d files (%s, %s reclaimable)\n",
+		plan.Created.Format("2006-01-02 15:04"), plan.Strategy, len(plan.Entries),
+		utils.HumanSize(plan.TotalBytes), utils.HumanSize(plan.ReclaimableBytes))
+	if !*yes && !confirm("Proceed? [y/N] ") {
+		fmt.Println("Aborted")
+		return 1
+	}
+
+	var removed, skipped, failed int
+	var freed int64
+	remover := cleanup.Remover{Audit: openAudit(*auditPath), Force: *force, Protect: patterns}
+	for _, r := range remover.Apply(plan) {
+		switch {
+		case r.Skipped:
+			skipped++
+			fmt.Printf("⏭️  skipped %s: %v\n", r.Path, r.Err)
+		case r.Err != nil:
+			failed++
+			fmt.Printf("❌ %s: %v\n", r.Path, r.Err)
+		default:
+			removed++
+			freed += r.Size
+			fmt.Printf("✅ %s\n", r.Path)
+		}
+	}
+
+	fmt.Printf("\nRemoved %d files (%s), skipped %d, failed %d\n", removed, utils.HumanSize(freed), skipped, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
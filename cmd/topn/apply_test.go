@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natemollica-nm/topn/internal/cleanup"
+)
+
+func TestApplyHonorsConfigProtect(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "disk.qcow2")
+	if err := os.WriteFile(keep, []byte("image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, errs := cleanup.NewPlan([]string{keep}, cleanup.Unlink)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	planPath := filepath.Join(dir, "plan.json")
+	if err := plan.WriteFile(planPath); err != nil {
+		t.Fatal(err)
+	}
+	cfg := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(cfg, []byte("[profiles.vm]\nprotect = [\"*.qcow2\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runApply([]string{"-yes", "-config", cfg, "-profile", "vm", "-audit-log", filepath.Join(dir, "audit.log"), planPath})
+	if _, err := os.Stat(keep); err != nil {
+		t.Errorf("file protected by the profile was removed: %v", err)
+	}
+}
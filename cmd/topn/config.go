@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/natemollica-nm/topn/internal/config"
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+// notConfigurable are flags that only make sense on the command line.
+var notConfigurable = map[string]bool{"config": true, "profile": true, "version": true}
+
+// mergedLists are repeatable flags that collect values from every source
+// instead of taking the highest one. A protect entry must not disappear
+// because a profile or flag sets protect too.
+var mergedLists = map[string]bool{"protect": true}
+
+func defaultConfigPath() string {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// envName is the environment variable that sets flag name, e.g. TOPN_OLDER_THAN.
+func envName(name string) string {
+	return "TOPN_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyConfig fills in the flags of fset that were not given on the command
+// line. In increasing order of precedence the sources are: built-in
+// defaults, the config file's top-level settings, the profile, TOPN_*
+// environment variables, and finally the command line. Repeatable flags
+// take their whole list from the highest source that sets them, except
+// mergedLists, which gather the values of all sources.
+func applyConfig(fset *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	fset.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	sources, err := configSources(fset)
+	if err != nil {
+		return err
+	}
+	for _, s := range sources {
+		for _, name := range s.Keys() {
+			f := fset.Lookup(name)
+			if f == nil || notConfigurable[name] {
+				return fmt.Errorf("unknown option %q in configuration", name)
+			}
+			if mergedLists[name] {
+				for _, v := range s[name] {
+					f.Value.Set(v)
+				}
+				continue
+			}
+			if explicit[name] {
+				continue
+			}
+			if err := setFlag(f, s[name]); err != nil {
+				return fmt.Errorf("option %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configSources returns the settings for the flags of fset from the config
+// file, the profile and TOPN_* environment variables, lowest precedence
+// first. The config path and profile name come from -config/-profile or
+// TOPN_CONFIG and TOPN_PROFILE.
+func configSources(fset *flag.FlagSet) ([]config.Settings, error) {
+	explicit := make(map[string]bool)
+	fset.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for _, name := range []string{"config", "profile"} {
+		if v, ok := os.LookupEnv(envName(name)); ok && !explicit[name] {
+			fset.Set(name, v)
+			explicit[name] = true
+		}
+	}
+	path := fset.Lookup("config").Value.String()
+	profile := fset.Lookup("profile").Value.String()
+
+	var sources []config.Settings
+	if path != "" {
+		file, err := config.Load(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit["config"]:
+			// No config file is fine unless one was asked for.
+		case err != nil:
+			return nil, err
+		default:
+			sources = append(sources, file.Defaults)
+			if profile != "" {
+				p, err := file.Profile(profile)
+				if err != nil {
+					return nil, err
+				}
+				sources = append(sources, p)
+			}
+		}
+	}
+	if profile != "" && len(sources) < 2 {
+		return nil, fmt.Errorf("profile %q requested but no config file was found at %s", profile, path)
+	}
+
+	env := config.Settings{}
+	fset.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok || notConfigurable[f.Name] {
+			return
+		}
+		if _, multi := f.Value.(*utils.MultiFlag); multi {
+			env[f.Name] = strings.Split(v, string(os.PathListSeparator))
+		} else {
+			env[f.Name] = []string{v}
+		}
+	})
+	return append(sources, env), nil
+}
+
+func setFlag(f *flag.Flag, vals []string) error {
+	if mf, ok := f.Value.(*utils.MultiFlag); ok {
+		*mf = nil
+		for _, v := range vals {
+			mf.Set(v)
+		}
+		return nil
+	}
+	if len(vals) != 1 {
+		return fmt.Errorf("takes a single value, got %d", len(vals))
+	}
+	return f.Value.Set(vals[0])
+}
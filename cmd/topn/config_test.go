@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	cfg := `min = "500M"
+top = 10
+exclude = ["*.git"]
+
+[profiles.ci]
+top = 20
+format = "json"
+exclude = ["node_modules", "*.cache"]
+`
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOPN_FORMAT", "csv")
+	t.Setenv("TOPN_PROFILE", "ci")
+
+	fset := flag.NewFlagSet("topn", flag.ContinueOnError)
+	minStr := fset.String("min", "1G", "")
+	top := fset.Int("top", 50, "")
+	format := fset.String("format", "table", "")
+	dir := fset.String("dir", "/home", "")
+	var excl utils.MultiFlag
+	fset.Var(&excl, "exclude", "")
+	fset.String("config", "", "")
+	fset.String("profile", "", "")
+	if err := fset.Parse([]string{"-config", path, "-dir", "/srv"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOPN_DIR", "/ignored")
+
+	if err := applyConfig(fset); err != nil {
+		t.Fatal(err)
+	}
+	if *minStr != "500M" {
+		t.Errorf("min = %q, want the config default", *minStr)
+	}
+	if *top != 20 {
+		t.Errorf("top = %d, want the profile's 20", *top)
+	}
+	if *format != "csv" {
+		t.Errorf("format = %q, want TOPN_FORMAT to beat the profile", *format)
+	}
+	if *dir != "/srv" {
+		t.Errorf("dir = %q, want the flag to beat TOPN_DIR", *dir)
+	}
+	if want := (utils.MultiFlag{"node_modules", "*.cache"}); !reflect.DeepEqual(excl, want) {
+		t.Errorf("exclude = %v, want the profile list %v", excl, want)
+	}
+}
+
+func TestApplyConfigUnknownOption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("minimum = \"1G\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fset := flag.NewFlagSet("topn", flag.ContinueOnError)
+	fset.String("config", path, "")
+	fset.String("profile", "", "")
+	if err := applyConfig(fset); err == nil {
+		t.Error("unknown option accepted")
+	}
+}
+
+func TestApplyConfigMergesProtect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	cfg := `protect = ["/srv/db"]
+
+[profiles.ci]
+protect = ["*.qcow2"]
+`
+	if err := os.WriteFile(path, []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TOPN_PROTECT", "/etc:/boot")
+
+	fset := flag.NewFlagSet("topn", flag.ContinueOnError)
+	var protect utils.MultiFlag
+	fset.Var(&protect, "protect", "")
+	fset.String("config", "", "")
+	fset.String("profile", "", "")
+	if err := fset.Parse([]string{"-config", path, "-profile", "ci", "-protect", "/home"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfig(fset); err != nil {
+		t.Fatal(err)
+	}
+	want := utils.MultiFlag{"/home", "/srv/db", "*.qcow2", "/etc", "/boot"}
+	if !reflect.DeepEqual(protect, want) {
+		t.Errorf("protect = %v, want every source's entries %v", protect, want)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/natemollica-nm/topn/internal/audit"
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+func defaultAuditPath() string {
+	path, err := audit.DefaultPath()
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// openAudit returns the audit log at path, or nil if path is empty.
+func openAudit(path string) *audit.Log {
+	if path == "" {
+		return nil
+	}
+	return audit.Open(path)
+}
+
+// runLog implements `topn log`, which queries the removal audit log.
+func runLog(args []string) int {
+	fs := flag.NewFlagSet("log", flag.ExitOnError)
+	path := fs.String("audit-log", defaultAuditPath(), "audit log to read")
+	since := fs.String("since", "", "only entries after an age (7d, 6mo) or date (2024-01-31)")
+	until := fs.String("until", "", "only entries before an age or date")
+	prefix := fs.String("prefix", "", "only entries for paths under this prefix")
+	asJSON := fs.Bool("json", false, "print raw JSON lines")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: topn log [-since AGE|DATE] [-until AGE|DATE] [-prefix PATH] [-json]\n\n")
+		fmt.Fprintf(fs.Output(), "Show removals recorded in the audit log.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var filter audit.Filter
+	now := time.Now()
+	var err error
+	if *since != "" {
+		if filter.Since, err = utils.ParseAge(*since, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing -since: %v\n", err)
+			return 2
+		}
+	}
+	if *until != "" {
+		if filter.Until, err = utils.ParseAge(*until, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing -until: %v\n", err)
+			return 2
+		}
+	}
+	filter.Prefix = *prefix
+
+	entries, err := audit.Query(*path, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading audit log: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			enc.Encode(e)
+		}
+		return 0
+	}
+	if len(entries) == 0 {
+		fmt.Println("No matching removals")
+		return 0
+	}
+	fmt.Printf("%-19s %-8s %-7s %-10s %-10s %s\n", "Time", "Outcome", "Via", "Size", "User", "Path")
+	for _, e := range entries {
+		path := e.Path
+		if e.Error != "" {
+			path += " (" + e.Error + ")"
+		}
+		fmt.Printf("%-19s %-8s %-7s %-10s %-10s %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.Outcome, e.Strategy,
+			utils.HumanSize(e.Size), e.User, path)
+	}
+	return 0
+}
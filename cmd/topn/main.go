@@ -10,6 +10,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/natemollica-nm/topn/internal/cleanup"
 	"github.com/natemollica-nm/topn/internal/scanner"
 	"github.com/natemollica-nm/topn/internal/ui"
 	"github.com/natemollica-nm/topn/internal/utils"
@@ -17,27 +18,101 @@ import (
 
 var version = "dev"
 
+const dateLayout = "2006-01-02"
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "apply":
+			os.Exit(runApply(os.Args[2:]))
+		case "log":
+			os.Exit(runLog(os.Args[2:]))
+		}
+	}
+
 	var (
-		dir      string
+		dirs     utils.MultiFlag
 		minStr   string
 		topN     int
 		workers  int
 		exclVals utils.MultiFlag
 		remove   bool
 		tui      bool
+		showErrs bool
 		showVer  bool
+		by       string
+		depth    int
+		usageStr string
+		xdev     bool
+		mounts   bool
+		follow   bool
+		followIn utils.MultiFlag
+		protect  utils.MultiFlag
+		cfgPath  string
+		ignores  bool
+		why      string
+		profile  string
+		olderStr string
+		newerStr string
+		timeStr  string
+		format   string
+		nulPaths bool
+		perm     bool
+		force    bool
+		archive  string
+		dryRun   bool
+		planPath string
+		auditLog string
 	)
 
-	flag.StringVar(&dir, "dir", os.Getenv("HOME"), "root directory to scan")
+	flag.Var(&dirs, "dir", "root directory to scan (repeatable; directories may also be given as arguments after all flags; default $HOME)")
 	flag.StringVar(&minStr, "min", "1G", "minimum file size (e.g. 1G, 500M, 250K)")
 	flag.IntVar(&topN, "top", 50, "keep only top N largest files")
 	flag.IntVar(&workers, "workers", 0, "number of workers (default: 4*GOMAXPROCS)")
-	flag.Var(&exclVals, "exclude", "glob/path to exclude (repeatable)")
+	flag.Var(&exclVals, "exclude", "gitignore-style pattern to exclude (repeatable); a leading / anchors it to the filesystem root")
+	flag.BoolVar(&ignores, "ignore-files", false, "also honor .gitignore and .topnignore files found during the walk")
+	flag.StringVar(&why, "why", "", "explain which exclude rule, if any, matches this path, then exit")
+	flag.StringVar(&olderStr, "older-than", "", "only files whose -time is older than an age (90d, 6mo, 1y) or date (2024-01-31)")
+	flag.StringVar(&newerStr, "newer-than", "", "only files whose -time is newer than an age or date")
+	flag.StringVar(&timeStr, "time", "mtime", "timestamp used by -older-than/-newer-than: mtime, atime or ctime")
+	flag.StringVar(&by, "by", "file", "rank individual files (file) or directory totals (dir)")
+	flag.StringVar(&usageStr, "usage", "apparent", "size used for -min, ranking and totals: apparent or allocated")
+	flag.IntVar(&depth, "depth", 1, "with -by dir, roll sizes up to directories at most N levels below -dir (0 for no limit)")
+	flag.BoolVar(&xdev, "xdev", false, "stay on the filesystem of -dir; do not descend into other mounts")
+	flag.BoolVar(&follow, "follow", false, "follow symlinks to files and directories")
+	flag.Var(&followIn, "follow-under", "with -follow, only follow links whose target is under this prefix (repeatable)")
+	flag.BoolVar(&mounts, "mounts", false, "print bytes found per mount point and filesystem type")
+	flag.StringVar(&format, "format", "table", "CLI output format: table, json, ndjson, csv or tsv")
+	flag.BoolVar(&nulPaths, "0", false, "print only result paths, NUL-terminated (for xargs -0)")
 	flag.BoolVar(&remove, "remove", false, "interactively remove files")
+	flag.Var(&protect, "protect", "show but never remove paths matching this glob or under this prefix (repeatable)")
+	flag.BoolVar(&force, "force", false, "with -remove/-tui, remove files even while a process holds them open")
+	flag.StringVar(&archive, "archive-dir", "", "in the TUI, directory the archive action moves files to")
+	flag.BoolVar(&perm, "permanent", false, "with -remove/-tui, delete files permanently instead of moving them to the trash")
+	flag.BoolVar(&dryRun, "dry-run", false, "in the TUI, write a removal plan instead of removing anything (implies -tui)")
+	flag.StringVar(&planPath, "plan", "topn-plan.json", "with -dry-run, where to write the plan (.sh for a shell script, else JSON)")
+	flag.StringVar(&auditLog, "audit-log", defaultAuditPath(), "append a record of every removal to this JSON-lines file (empty to disable)")
 	flag.BoolVar(&tui, "tui", false, "use interactive terminal UI")
+	flag.BoolVar(&showErrs, "errors", false, "list every walk/stat error after the results")
 	flag.BoolVar(&showVer, "version", false, "show version")
+	flag.StringVar(&cfgPath, "config", defaultConfigPath(), "configuration file with defaults and profiles")
+	flag.StringVar(&profile, "profile", "", "use the settings of this profile from the configuration file")
 	flag.Parse()
+	// Directories given as arguments are -dir flags, so they replace the
+	// configured directories instead of adding to them. Parsing stops at
+	// the first of them, so a flag after one would be taken for a directory.
+	for _, dir := range flag.Args() {
+		if strings.HasPrefix(dir, "-") {
+			fmt.Fprintf(os.Stderr, "Error: flag %s follows a directory argument; directories must come after all flags\n", dir)
+			os.Exit(2)
+		}
+		flag.Set("dir", dir)
+	}
+
+	if err := applyConfig(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	if showVer {
 		fmt.Printf("topn %s\n", version)
@@ -54,34 +129,119 @@ func main() {
 		os.Exit(1)
 	}
 
-	root, err := filepath.Abs(dir)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error resolving directory: %v\n", err)
+	if !formats[format] {
+		fmt.Fprintf(os.Stderr, "Error: -format must be table, json, ndjson, csv or tsv, got %q\n", format)
+		os.Exit(1)
+	}
+	if nulPaths && format != "table" {
+		fmt.Fprintf(os.Stderr, "Error: -0 cannot be combined with -format %s\n", format)
+		os.Exit(1)
+	}
+
+	mode, ok := scanner.ParseMode(by)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: -by must be 'file' or 'dir', got %q\n", by)
 		os.Exit(1)
 	}
 
-	if st, err := os.Stat(root); err != nil || !st.IsDir() {
-		fmt.Fprintf(os.Stderr, "Error: '%s' is not a valid directory\n", root)
+	usage, ok := scanner.ParseUsage(usageStr)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: -usage must be 'apparent' or 'allocated', got %q\n", usageStr)
 		os.Exit(1)
 	}
 
+	timeField, ok := scanner.ParseTimeField(timeStr)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: -time must be 'mtime', 'atime' or 'ctime', got %q\n", timeStr)
+		os.Exit(1)
+	}
+
+	var olderThan, newerThan time.Time
+	now := time.Now()
+	if olderStr != "" {
+		if olderThan, err = utils.ParseAge(olderStr, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing -older-than: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if newerStr != "" {
+		if newerThan, err = utils.ParseAge(newerStr, now); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing -newer-than: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(dirs) == 0 {
+		dirs = append(dirs, os.Getenv("HOME"))
+	}
+	roots := make([]string, len(dirs))
+	for i, dir := range dirs {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving directory: %v\n", err)
+			os.Exit(1)
+		}
+		if st, err := os.Stat(root); err != nil || !st.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: '%s' is not a valid directory\n", root)
+			os.Exit(1)
+		}
+		roots[i] = root
+	}
+	rootList := strings.Join(roots, ", ")
+
 	config := scanner.Config{
-		Root:     root,
+		Root:     roots[0],
+		Roots:    roots,
 		MinBytes: minBytes,
 		TopN:     topN,
 		Workers:  workers,
 		Excludes: exclVals,
+		Mode:     mode,
+		Depth:    depth,
+		Usage:    usage,
+
+		IgnoreFiles: ignores,
+
+		OlderThan: olderThan,
+		NewerThan: newerThan,
+		TimeField: timeField,
+
+		OneFileSystem: xdev,
+		Mounts:        mounts,
+		Follow:        follow,
+		FollowUnder:   followIn,
+	}
+
+	if why != "" {
+		if err := explain(config, why); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
 	// Use TUI if requested or if remove flag is set
-	if tui || remove {
-		model := ui.NewModel(config)
+	if tui || remove || dryRun {
+		opts := ui.Options{Strategy: cleanup.Trash, DryRun: dryRun, PlanPath: planPath, Audit: openAudit(auditLog)}
+		if perm {
+			opts.Strategy = cleanup.Unlink
+		}
+		opts.Force = force
+		opts.ArchiveDir = archive
+		if opts.Protect, err = cleanup.Protect(protect).Abs(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if path, err := cleanup.DefaultHistoryPath(); err == nil {
+			opts.History = cleanup.OpenHistory(path)
+		}
+		model := ui.NewModel(config, opts)
 		p := tea.NewProgram(
 			model,
 			tea.WithAltScreen(),
 			tea.WithMouseCellMotion(),
 		)
-		
+
 		if _, err := p.Run(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
 			os.Exit(1)
@@ -89,44 +249,204 @@ func main() {
 		return
 	}
 
+	if nulPaths || format != "table" {
+		start := time.Now()
+		results, stats := scanner.New(config).Scan()
+		if nulPaths {
+			err = writeNulPaths(os.Stdout, results)
+		} else {
+			err = writeReport(os.Stdout, format, newReport(config, results, stats, time.Since(start)))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Classic CLI mode with enhanced output
-	fmt.Printf("🔍 Scanning %s for files >= %s...\n", root, minStr)
-	
+	if mode == scanner.ByDir {
+		fmt.Printf("🔍 Scanning %s for directories >= %s...\n", rootList, minStr)
+	} else {
+		fmt.Printf("🔍 Scanning %s for files >= %s...\n", rootList, minStr)
+	}
+
 	s := scanner.New(config)
 	start := time.Now()
 	results, stats := s.Scan()
 	elapsed := time.Since(start).Round(time.Millisecond)
 
 	fmt.Printf("\n✅ Scan complete in %s\n", elapsed)
-	fmt.Printf("📊 Files seen: %d, kept: %d (>= %s)\n\n", 
-		stats.FilesSeen, stats.FilesKept, minStr)
+	kept := "kept"
+	if mode == scanner.ByDir {
+		kept = "directories kept"
+	}
+	fmt.Printf("📊 Files seen: %d, %s: %d (>= %s)\n",
+		stats.FilesSeen, kept, stats.FilesKept, minStr)
+	if stats.DuplicateLinks > 0 {
+		fmt.Printf("🔗 Duplicate links folded: %d (each inode is listed once)\n", stats.DuplicateLinks)
+	}
+	if stats.SymlinkLoops > 0 {
+		fmt.Printf("🔁 Symlinks into already scanned directories skipped: %d\n", stats.SymlinkLoops)
+	}
+	if n := len(stats.SkippedMounts); n > 0 {
+		fmt.Printf("🚧 Skipped %d directories on other filesystems (-xdev)\n", n)
+	}
+	printErrorSummary(stats, showErrs)
+	fmt.Println()
 
 	if len(results) == 0 {
 		fmt.Println("🎉 No large files found!")
+	} else if mode == scanner.ByDir {
+		printDirResults(results, timeField)
+		fmt.Printf("\n💡 Tip: Use -depth to roll sizes up deeper or shallower\n")
+	} else {
+		printResults(results, timeField)
+		fmt.Printf("\n💡 Tip: Use -tui or -remove for interactive file management\n")
+	}
+
+	if len(roots) > 1 {
+		printRoots(stats.Roots, results)
+	}
+	if mounts {
+		printMounts(stats.Mounts)
+	}
+	if showErrs {
+		printErrors(stats)
+	}
+}
+
+func printMounts(mounts []scanner.MountUsage) {
+	fmt.Printf("\n%-10s %-10s %-10s %s\n", "Size", "Files", "Type", "Mount")
+	fmt.Printf("%-10s %-10s %-10s %s\n", "----", "-----", "----", strings.Repeat("-", 30))
+	for _, m := range mounts {
+		mp := m.MountPoint
+		if mp == "" {
+			mp = fmt.Sprintf("(device %#x)", m.Dev)
+		}
+		fsType := m.FSType
+		if fsType == "" {
+			fsType = "?"
+		}
+		fmt.Printf("%-10s %-10d %-10s %s\n", utils.HumanSize(m.Bytes), m.Files, fsType, mp)
+	}
+}
+
+// explain prints which exclude rule decides whether path is scanned.
+func explain(config scanner.Config, path string) error {
+	v, err := scanner.Explain(config, path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case v.Via != "":
+		fmt.Printf("%s is excluded: its parent %s matches %s\n", path, v.Via, v.Rule)
+	case v.Excluded:
+		fmt.Printf("%s is excluded by %s\n", path, v.Rule)
+	case v.Rule != nil:
+		fmt.Printf("%s is included: %s re-includes it\n", path, v.Rule)
+	default:
+		fmt.Printf("%s is not excluded: no rule matches\n", path)
+	}
+	return nil
+}
+
+// printRoots breaks a multi-root scan down by root, including how many of
+// the ranked results each root contributed.
+func printRoots(roots []scanner.RootStats, results []scanner.FileItem) {
+	ranked := make(map[string]int)
+	for _, it := range results {
+		ranked[it.Root]++
+	}
+	fmt.Printf("\n%-10s %-10s %-10s %-10s %s\n", "Size", "Files", "Kept", "Ranked", "Root")
+	fmt.Printf("%-10s %-10s %-10s %-10s %s\n", "----", "-----", "----", "------", strings.Repeat("-", 30))
+	for _, r := range roots {
+		root := r.Root
+		if len(r.Nested) > 0 {
+			root += fmt.Sprintf(" (includes %s)", strings.Join(r.Nested, ", "))
+		}
+		fmt.Printf("%-10s %-10d %-10d %-10d %s\n", utils.HumanSize(r.BytesSeen), r.FilesSeen, r.FilesKept, ranked[r.Root], root)
+	}
+}
+
+func printDirResults(results []scanner.FileItem, field scanner.TimeField) {
+	fmt.Printf("%-5s %-10s %-10s %-10s %s\n", "Rank", "Size", "Files", field.Title(), "Directory")
+	fmt.Printf("%-5s %-10s %-10s %-10s %s\n", "----", "----", "-----", "--------", strings.Repeat("-", 50))
+
+	for i, item := range results {
+		path := item.Path + "/"
+		if len(path) > 60 {
+			path = "..." + path[len(path)-57:]
+		}
+		fmt.Printf("%-5s %-10s %-10d %-10s %s\n", fmt.Sprintf("#%d", i+1), utils.HumanSize(item.Size), item.Files, item.Time.Format(dateLayout), path)
+	}
+}
+
+func printErrorSummary(stats scanner.Stats, listed bool) {
+	c := stats.ErrorCounts
+	if c.Total() == 0 {
 		return
 	}
+	fmt.Printf("⚠️  Errors: %d (permission denied: %d, not found: %d, I/O: %d, other: %d)\n",
+		c.Total(), c.Permission, c.NotExist, c.IO, c.Other)
+	if !listed {
+		fmt.Println("   Results may undercount; rerun with -errors to list them")
+	}
+}
 
-	printResults(results)
-	
-	if len(results) > 0 {
-		fmt.Printf("\n💡 Tip: Use -tui or -remove for interactive file management\n")
+func printErrors(stats scanner.Stats) {
+	if len(stats.Errors) == 0 {
+		return
+	}
+	fmt.Printf("\n%-10s %-26s %s\n", "Op", "Error", "Path")
+	fmt.Printf("%-10s %-26s %s\n", "--", "-----", strings.Repeat("-", 50))
+	for _, e := range stats.Errors {
+		fmt.Printf("%-10s %-26s %s\n", e.Op, e.Err, e.Path)
+	}
+	if dropped := stats.ErrorCounts.Total() - int64(len(stats.Errors)); dropped > 0 {
+		fmt.Printf("... and %d more not recorded\n", dropped)
 	}
 }
 
-func printResults(results []scanner.FileItem) {
-	fmt.Printf("%-5s %-10s %s\n", "Rank", "Size", "Path")
-	fmt.Printf("%-5s %-10s %s\n", "----", "----", strings.Repeat("-", 50))
-	
+func printResults(results []scanner.FileItem, field scanner.TimeField) {
+	fmt.Printf("%-5s %-10s %-8s %-10s %s\n", "Rank", "Size", "Alloc", field.Title(), "Path")
+	fmt.Printf("%-5s %-10s %-8s %-10s %s\n", "----", "----", "-----", "--------", strings.Repeat("-", 50))
+
+	sparse := false
 	for i, item := range results {
 		rank := fmt.Sprintf("#%d", i+1)
 		size := utils.HumanSize(item.Size)
-		
+		alloc := allocColumn(item)
+		sparse = sparse || item.Sparse()
+
 		// Truncate long paths for better display
 		path := item.Path
 		if len(path) > 70 {
 			path = "..." + path[len(path)-67:]
 		}
-		
-		fmt.Printf("%-5s %-10s %s\n", rank, size, path)
+
+		if item.Target != "" {
+			path += " -> " + item.Target
+		}
+		if len(item.Links) > 0 {
+			path += fmt.Sprintf(" (+%d links)", len(item.Links))
+		}
+		fmt.Printf("%-5s %-10s %-8s %-10s %s\n", rank, size, alloc, item.Time.Format(dateLayout), path)
+		for _, link := range item.Links {
+			fmt.Printf("%-5s %-10s %-8s %-10s ↳ %s\n", "", "", "", "", link)
+		}
+	}
+	if sparse {
+		fmt.Printf("\n⚠️  Files marked ! are sparse or compressed: their apparent size overstates the disk space they use\n")
+	}
+}
+
+// allocColumn renders allocated bytes as a percentage of the apparent size,
+// marking files whose apparent size is misleading.
+func allocColumn(item scanner.FileItem) string {
+	col := fmt.Sprintf("%.0f%%", item.AllocRatio()*100)
+	if item.Sparse() {
+		col += " !"
 	}
-}
\ No newline at end of file
+	return col
+}
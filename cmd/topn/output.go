@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+// schemaVersion is bumped whenever a field is renamed or removed from the
+// machine-readable output. Adding fields does not change it.
+const schemaVersion = 1
+
+var formats = map[string]bool{"table": true, "json": true, "ndjson": true, "csv": true, "tsv": true}
+
+type resultRecord struct {
+	Rank          int       `json:"rank"`
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	HumanSize     string    `json:"human_size"`
+	ApparentSize  int64     `json:"apparent_size"`
+	AllocatedSize int64     `json:"allocated_size"`
+	Time          time.Time `json:"time"`
+	IsDir         bool      `json:"is_dir"`
+	Files         int64     `json:"files"`
+	Target        string    `json:"target"`
+	Links         []string  `json:"links"`
+	Root          string    `json:"root"`
+}
+
+type rootRecord struct {
+	Root      string   `json:"root"`
+	FilesSeen int64    `json:"files_seen"`
+	FilesKept int64    `json:"files_kept"`
+	BytesSeen int64    `json:"bytes_seen"`
+	Nested    []string `json:"nested"`
+}
+
+type errorRecord struct {
+	Path  string `json:"path"`
+	Op    string `json:"op"`
+	Kind  string `json:"kind"`
+	Errno int    `json:"errno"`
+	Error string `json:"error"`
+}
+
+type mountRecord struct {
+	MountPoint string `json:"mount_point"`
+	FSType     string `json:"fs_type"`
+	Source     string `json:"source"`
+	Bytes      int64  `json:"bytes"`
+	Files      int64  `json:"files"`
+}
+
+type statsRecord struct {
+	// Root is the first scan root, kept for single-root consumers; Roots
+	// lists every root.
+	Root           string        `json:"root"`
+	Roots          []rootRecord  `json:"roots"`
+	Mode           string        `json:"mode"`
+	Usage          string        `json:"usage"`
+	MinBytes       int64         `json:"min_bytes"`
+	ElapsedMS      int64         `json:"elapsed_ms"`
+	FilesSeen      int64         `json:"files_seen"`
+	FilesKept      int64         `json:"files_kept"`
+	DuplicateLinks int64         `json:"duplicate_links"`
+	SymlinkLoops   int64         `json:"symlink_loops"`
+	Partial        bool          `json:"partial"`
+	ErrorCount     int64         `json:"error_count"`
+	Errors         []errorRecord `json:"errors"`
+	SkippedMounts  []string      `json:"skipped_mounts"`
+	Mounts         []mountRecord `json:"mounts"`
+}
+
+type report struct {
+	Schema  int            `json:"schema"`
+	Stats   statsRecord    `json:"stats"`
+	Results []resultRecord `json:"results"`
+}
+
+func newReport(config scanner.Config, results []scanner.FileItem, stats scanner.Stats, elapsed time.Duration) report {
+	r := report{
+		Schema: schemaVersion,
+		Stats: statsRecord{
+			Root:           config.Root,
+			Mode:           config.Mode.String(),
+			Usage:          config.Usage.String(),
+			MinBytes:       config.MinBytes,
+			ElapsedMS:      elapsed.Milliseconds(),
+			FilesSeen:      stats.FilesSeen,
+			FilesKept:      stats.FilesKept,
+			DuplicateLinks: stats.DuplicateLinks,
+			SymlinkLoops:   stats.SymlinkLoops,
+			Partial:        stats.Partial,
+			ErrorCount:     stats.ErrorCounts.Total(),
+			Errors:         []errorRecord{},
+			SkippedMounts:  append([]string{}, stats.SkippedMounts...),
+			Mounts:         []mountRecord{},
+			Roots:          []rootRecord{},
+		},
+		Results: make([]resultRecord, len(results)),
+	}
+	for _, e := range stats.Errors {
+		r.Stats.Errors = append(r.Stats.Errors, errorRecord{
+			Path:  e.Path,
+			Op:    e.Op,
+			Kind:  e.Kind.String(),
+			Errno: int(e.Errno),
+			Error: e.Err.Error(),
+		})
+	}
+	for _, rs := range stats.Roots {
+		r.Stats.Roots = append(r.Stats.Roots, rootRecord{
+			Root:      rs.Root,
+			FilesSeen: rs.FilesSeen,
+			FilesKept: rs.FilesKept,
+			BytesSeen: rs.BytesSeen,
+			Nested:    append([]string{}, rs.Nested...),
+		})
+	}
+	for _, m := range stats.Mounts {
+		r.Stats.Mounts = append(r.Stats.Mounts, mountRecord{
+			MountPoint: m.MountPoint,
+			FSType:     m.FSType,
+			Source:     m.Source,
+			Bytes:      m.Bytes,
+			Files:      m.Files,
+		})
+	}
+	for i, it := range results {
+		r.Results[i] = resultRecord{
+			Rank:          i + 1,
+			Path:          it.Path,
+			Size:          it.Size,
+			HumanSize:     utils.HumanSize(it.Size),
+			ApparentSize:  it.Apparent,
+			AllocatedSize: it.Allocated,
+			Time:          it.Time,
+			IsDir:         it.IsDir,
+			Files:         it.Files,
+			Target:        it.Target,
+			Links:         append([]string{}, it.Links...),
+			Root:          it.Root,
+		}
+	}
+	return r
+}
+
+// writeReport renders r in one of the machine-readable formats.
+func writeReport(w io.Writer, format string, r report) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case "ndjson":
+		return writeNDJSON(w, r)
+	case "csv":
+		return writeDelimited(w, ',', r)
+	case "tsv":
+		return writeDelimited(w, '\t', r)
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// writeNDJSON emits one "result" object per line followed by a final
+// "stats" object.
+func writeNDJSON(w io.Writer, r report) error {
+	enc := json.NewEncoder(w)
+	for _, res := range r.Results {
+		line := struct {
+			Type   string `json:"type"`
+			Schema int    `json:"schema"`
+			resultRecord
+		}{"result", r.Schema, res}
+		if err := enc.Encode(line); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(struct {
+		Type   string `json:"type"`
+		Schema int    `json:"schema"`
+		statsRecord
+	}{"stats", r.Schema, r.Stats})
+}
+
+var delimitedHeader = []string{
+	"rank", "path", "size", "human_size", "apparent_size", "allocated_size",
+	"time", "is_dir", "files", "target", "link_count", "root",
+}
+
+// writeDelimited emits the results as CSV or TSV with a header row. Stats
+// are not included; use json or ndjson for those.
+func writeDelimited(w io.Writer, comma rune, r report) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(delimitedHeader); err != nil {
+		return err
+	}
+	for _, res := range r.Results {
+		err := cw.Write([]string{
+			strconv.Itoa(res.Rank),
+			res.Path,
+			strconv.FormatInt(res.Size, 10),
+			res.HumanSize,
+			strconv.FormatInt(res.ApparentSize, 10),
+			strconv.FormatInt(res.AllocatedSize, 10),
+			res.Time.Format(time.RFC3339),
+			strconv.FormatBool(res.IsDir),
+			strconv.FormatInt(res.Files, 10),
+			res.Target,
+			strconv.Itoa(len(res.Links)),
+			res.Root,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeNulPaths writes each result path terminated by a NUL byte, for
+// xargs -0.
+func writeNulPaths(w io.Writer, results []scanner.FileItem) error {
+	bw := bufio.NewWriter(w)
+	for _, it := range results {
+		bw.WriteString(it.Path)
+		bw.WriteByte(0)
+	}
+	return bw.Flush()
+}
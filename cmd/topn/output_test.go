@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+func testReport() report {
+	results := []scanner.FileItem{{Path: "/a,b", Size: 2048, Apparent: 2048, Allocated: 4096}}
+	return newReport(scanner.Config{Root: "/"}, results, scanner.Stats{FilesSeen: 3, FilesKept: 1}, time.Second)
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReport(&buf, "csv", testReport()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want header and one row", len(lines))
+	}
+	if lines[0] != strings.Join(delimitedHeader, ",") {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], `1,"/a,b",2048,2.0K,2048,4096,`) {
+		t.Errorf("row = %q", lines[1])
+	}
+}
+
+func TestWriteReportNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeReport(&buf, "ndjson", testReport()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want one result and one stats line", len(lines))
+	}
+	var res, stats map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &res); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if res["type"] != "result" || res["path"] != "/a,b" || res["size"] != float64(2048) {
+		t.Errorf("result line = %v", res)
+	}
+	if stats["type"] != "stats" || stats["files_seen"] != float64(3) {
+		t.Errorf("stats line = %v", stats)
+	}
+}
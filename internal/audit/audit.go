@@ -0,0 +1,155 @@
+// Package audit keeps an append-only JSON-lines record of every removal.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Outcomes recorded in Entry.Outcome.
+const (
+	OutcomeOK      = "ok"
+	OutcomeError   = "error"
+	OutcomeSkipped = "skipped"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Host     string    `json:"host"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mtime"`
+	Dev      uint64    `json:"dev"`
+	Inode    uint64    `json:"inode"`
+	Strategy string    `json:"strategy"`
+	Outcome  string    `json:"outcome"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Log appends entries to a file. A nil *Log discards them.
+type Log struct {
+	path string
+	user string
+	host string
+}
+
+// DefaultPath returns $XDG_STATE_HOME/topn/audit.jsonl, falling back to
+// ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultPath() (string, error) {
+	dir, err := StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "audit.jsonl"), nil
+}
+
+// StateDir returns the directory topn keeps its state in.
+func StateDir() (string, error) {
+	if state := os.Getenv("XDG_STATE_HOME"); state != "" {
+		return filepath.Join(state, "topn"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "topn"), nil
+}
+
+// Open returns a Log writing to path. The file is created on first use.
+func Open(path string) *Log {
+	l := &Log{path: path, user: os.Getenv("USER")}
+	if u, err := user.Current(); err == nil {
+		l.user = u.Username
+	}
+	l.host, _ = os.Hostname()
+	return l
+}
+
+// Path returns the file the log writes to.
+func (l *Log) Path() string { return l.path }
+
+// Record appends e, filling in the time, user and host.
+func (l *Log) Record(e Entry) error {
+	if l == nil {
+		return nil
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.User, e.Host = l.user, l.host
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Filter selects audit entries. Zero fields match everything.
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	Prefix string
+}
+
+func (f Filter) match(e Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	if f.Prefix != "" {
+		prefix := strings.TrimSuffix(f.Prefix, "/")
+		if e.Path != prefix && !strings.HasPrefix(e.Path, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// Query reads the log at path and returns the entries matching f in the
+// order they were written. A missing log yields no entries.
+func Query(path string, f Filter) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(file)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return entries, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if f.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, sc.Err()
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topn", "audit.jsonl")
+	l := Open(path)
+
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for i, p := range []string{"/srv/a", "/srv/ab/c", "/home/x"} {
+		e := Entry{Time: base.Add(time.Duration(i) * time.Hour), Path: p, Strategy: "trash", Outcome: OutcomeOK}
+		if err := l.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := Query(path, Filter{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Query() = %d entries, %v; want 3", len(all), err)
+	}
+	if all[0].Host == "" {
+		t.Error("host was not recorded")
+	}
+
+	got, err := Query(path, Filter{Prefix: "/srv/a"})
+	if err != nil || len(got) != 1 || got[0].Path != "/srv/a" {
+		t.Errorf("prefix query = %+v, %v; want only /srv/a", got, err)
+	}
+
+	got, err = Query(path, Filter{Since: base.Add(30 * time.Minute), Until: base.Add(90 * time.Minute)})
+	if err != nil || len(got) != 1 || got[0].Path != "/srv/ab/c" {
+		t.Errorf("date query = %+v, %v; want only /srv/ab/c", got, err)
+	}
+
+	if got, err := Query(filepath.Join(t.TempDir(), "missing"), Filter{}); err != nil || got != nil {
+		t.Errorf("missing log = %v, %v; want nothing", got, err)
+	}
+}
@@ -0,0 +1,135 @@
+package cleanup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/natemollica-nm/topn/internal/trash"
+)
+
+// Extension returns the suffix a compression strategy appends, or "" for
+// strategies that do not compress.
+func (s Strategy) Extension() string {
+	switch s {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// Check reports whether r can act at all, e.g. that the zstd binary is
+// installed or that the archive directory exists.
+func (r Remover) Check() error {
+	switch r.Strategy {
+	case Zstd:
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return fmt.Errorf("zstd is not installed")
+		}
+	case Archive:
+		if r.ArchiveDir == "" {
+			return fmt.Errorf("no archive directory set")
+		}
+		info, err := os.Stat(r.ArchiveDir)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", r.ArchiveDir)
+		}
+	}
+	return nil
+}
+
+// compress replaces path with a compressed copy named path+s.Extension().
+// The copy is written to a temporary file in the same directory and renamed
+// into place before the original is removed, so at no point is neither
+// present. It keeps the original's mode, owner and modification time.
+func compress(path string, s Strategy) (string, error) {
+	dst := path + s.Extension()
+	if _, err := os.Lstat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists", dst)
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	ok := false
+	defer func() {
+		if !ok {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if s == Zstd {
+		var stderr bytes.Buffer
+		cmd := exec.Command("zstd", "-q", "-c", "--", path)
+		cmd.Stdout, cmd.Stderr = tmp, &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("zstd %s: %v: %s", path, err, strings.TrimSpace(stderr.String()))
+		}
+	} else {
+		zw := gzip.NewWriter(tmp)
+		zw.Name, zw.ModTime = filepath.Base(path), info.ModTime()
+		if _, err := io.Copy(zw, in); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	chown(tmp, info)
+	if err := tmp.Sync(); err != nil {
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	ok = true
+	return dst, os.Remove(path)
+}
+
+// archive moves path into dir, adding a numeric suffix if a file of the same
+// name is already there.
+func archive(path, dir string) (string, error) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	dst := filepath.Join(dir, base)
+	for n := 1; ; n++ {
+		if _, err := os.Lstat(dst); os.IsNotExist(err) {
+			break
+		}
+		dst = filepath.Join(dir, stem+"."+strconv.Itoa(n)+ext)
+	}
+	return dst, trash.Move(path, dst)
+}
@@ -0,0 +1,110 @@
+package cleanup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	data := bytes.Repeat([]byte("GET /healthz 200\n"), 4096)
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	res := Remover{Strategy: Gzip}.Remove(path)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.Dest != path+".gz" {
+		t.Errorf("Dest = %q, want %q", res.Dest, path+".gz")
+	}
+	if res.Reclaimed <= 0 {
+		t.Errorf("Reclaimed = %d, want > 0", res.Reclaimed)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("original still exists: %v", err)
+	}
+
+	f, err := os.Open(res.Dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if info, _ := f.Stat(); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decompressed %d bytes (%v), want the original %d", len(got), err, len(data))
+	}
+
+	// Compressing again must not clobber the existing .gz.
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if res := (Remover{Strategy: Gzip}).Remove(path); res.Err == nil {
+		t.Error("compress over an existing .gz succeeded")
+	}
+	if _, err := os.Lstat(path); err != nil {
+		t.Errorf("original lost after failed compress: %v", err)
+	}
+}
+
+func TestCompressZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	path := filepath.Join(t.TempDir(), "core")
+	if err := os.WriteFile(path, make([]byte, 1<<16), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	res := Remover{Strategy: Zstd}.Remove(path)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	out, err := exec.Command("zstd", "-q", "-d", "-c", res.Dest).Output()
+	if err != nil || len(out) != 1<<16 {
+		t.Errorf("zstd -d gave %d bytes (%v), want %d", len(out), err, 1<<16)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir := t.TempDir()
+	archiveDir := filepath.Join(dir, "archive")
+	if err := os.Mkdir(archiveDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(archiveDir, "dump.tar"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "dump.tar")
+	if err := os.WriteFile(path, []byte("payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res := Remover{Strategy: Archive, ArchiveDir: archiveDir}.Remove(path)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if want := filepath.Join(archiveDir, "dump.1.tar"); res.Dest != want {
+		t.Errorf("Dest = %q, want %q", res.Dest, want)
+	}
+	if res.Reclaimed != 0 {
+		t.Errorf("Reclaimed = %d, want 0 within one filesystem", res.Reclaimed)
+	}
+
+	if res := (Remover{Strategy: Archive}).Remove(res.Dest); res.Err == nil {
+		t.Error("archive without a directory succeeded")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package cleanup
+
+import (
+	"os"
+	"syscall"
+)
+
+// chown gives f the owner of info, best effort: it only succeeds when
+// running as root or as the owner already.
+func chown(f *os.File, info os.FileInfo) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		f.Chown(int(st.Uid), int(st.Gid))
+	}
+}
@@ -0,0 +1,239 @@
+// Package cleanup carries out removals and other space-reclaiming actions
+// chosen in the UI.
+package cleanup
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/natemollica-nm/topn/internal/audit"
+	"github.com/natemollica-nm/topn/internal/openfiles"
+	"github.com/natemollica-nm/topn/internal/scanner"
+	"github.com/natemollica-nm/topn/internal/trash"
+)
+
+// Strategy selects how a file is removed.
+type Strategy string
+
+const (
+	// Trash moves files to the freedesktop.org Trash so they can be restored.
+	Trash Strategy = "trash"
+	// Unlink deletes files permanently.
+	Unlink Strategy = "unlink"
+	// Truncate empties files in place, which frees their space even while
+	// a process holds them open.
+	Truncate Strategy = "truncate"
+	// Gzip and Zstd replace files with a compressed copy.
+	Gzip Strategy = "gzip"
+	Zstd Strategy = "zstd"
+	// Archive moves files into Remover.ArchiveDir.
+	Archive Strategy = "archive"
+)
+
+// OpenError reports a file that was skipped because processes hold it open.
+type OpenError struct {
+	Path  string
+	Procs []openfiles.Process
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s is open by %s", e.Path, openfiles.Describe(e.Procs))
+}
+
+// ReplacedError reports a file that was skipped because a different file
+// now has its path.
+type ReplacedError struct {
+	Path string
+}
+
+func (e *ReplacedError) Error() string {
+	return fmt.Sprintf("%s was replaced by a different file since it was scanned", e.Path)
+}
+
+// Result is the outcome of one removal.
+type Result struct {
+	Path string
+	Size int64
+	// Skipped is set when the file was left alone, e.g. because it changed
+	// after the plan was made. Err says why.
+	Skipped bool
+	Err     error
+	// Trashed describes where the file went when it was moved to the trash.
+	Trashed *trash.Entry
+	// Dest is the compressed or archived file that replaced Path.
+	Dest string
+	// Reclaimed is the allocated space actually freed on Path's filesystem.
+	// It is 0 when the file only moved within it, as with the trash, and can
+	// be negative if compression made the file bigger.
+	Reclaimed int64
+}
+
+// Remover removes files with one strategy and records every attempt,
+// successful or not, in the audit log.
+type Remover struct {
+	Strategy Strategy
+	// Audit receives one entry per file. A nil log records nothing.
+	Audit *audit.Log
+	// Force removes files even while processes hold them open. Otherwise
+	// they are skipped with an *OpenError, since removing them frees nothing.
+	Force bool
+	// ArchiveDir is where the Archive strategy moves files.
+	ArchiveDir string
+	// Protect rejects matching paths with a *ProtectedError, whoever asked
+	// for their removal.
+	Protect Protect
+}
+
+// Remove removes path.
+func (r Remover) Remove(path string) Result {
+	return r.remove(path, r.holders([]string{path}))
+}
+
+// holders looks up the processes holding paths open, or returns nil when
+// open files need not be skipped.
+func (r Remover) holders(paths []string) map[string][]openfiles.Process {
+	if r.Force || r.Strategy == Truncate {
+		return nil
+	}
+	held, _ := openfiles.Holders(paths)
+	return held
+}
+
+func (r Remover) remove(path string, held map[string][]openfiles.Process) Result {
+	if pat, ok := r.Protect.Match(path); ok {
+		return r.Skip(path, &ProtectedError{Path: path, Pattern: pat})
+	}
+	if procs := held[path]; len(procs) > 0 {
+		return r.Skip(path, &OpenError{Path: path, Procs: procs})
+	}
+	it, statErr := scanner.StatFile(path)
+	res := Result{Path: path, Size: it.Apparent, Err: statErr}
+	if statErr == nil {
+		r.act(it, &res)
+	}
+	return r.record(it, res)
+}
+
+// RemoveAll removes the files of items and records the ones that went to
+// the trash as one batch in h, so they can be restored together with
+// History.Undo. An item whose path now holds a different file than the
+// one scanned is skipped with a *ReplacedError.
+func (r Remover) RemoveAll(items []scanner.FileItem, h *History) ([]Result, error) {
+	paths := make([]string, len(items))
+	for i, it := range items {
+		paths[i] = it.Path
+	}
+	results := make([]Result, 0, len(items))
+	batch := Batch{Time: time.Now()}
+	held := r.holders(paths)
+	for _, it := range items {
+		var res Result
+		if replaced(it) {
+			res = r.Skip(it.Path, &ReplacedError{Path: it.Path})
+		} else {
+			res = r.remove(it.Path, held)
+		}
+		if res.Trashed != nil {
+			batch.Entries = append(batch.Entries, *res.Trashed)
+		}
+		results = append(results, res)
+	}
+	return results, h.Push(batch)
+}
+
+// replaced reports whether the path of it now holds a different file. A
+// file reached through a followed symlink was scanned as its target. Items
+// without an identity, and paths that are gone, are left to the removal to
+// report.
+func replaced(it scanner.FileItem) bool {
+	if it.ID == (scanner.FileID{}) {
+		return false
+	}
+	cur, err := scanner.StatFile(it.Path)
+	if err != nil || cur.ID == it.ID {
+		return false
+	}
+	if it.Target != "" {
+		if t, err := scanner.StatFile(it.Target); err == nil && t.ID == it.ID {
+			return false
+		}
+	}
+	return true
+}
+
+// Skip records that path was deliberately left alone for reason.
+func (r Remover) Skip(path string, reason error) Result {
+	it, _ := scanner.StatFile(path)
+	return r.record(it, Result{Path: path, Size: it.Apparent, Skipped: true, Err: reason})
+}
+
+// record writes res to the audit log. A failure to log turns a successful
+// removal into an error so that it is not silently unaudited.
+func (r Remover) record(it scanner.FileItem, res Result) Result {
+	e := audit.Entry{
+		Path:     res.Path,
+		Size:     it.Apparent,
+		ModTime:  it.Time,
+		Dev:      it.ID.Dev,
+		Inode:    it.ID.Ino,
+		Strategy: string(r.Strategy),
+		Outcome:  audit.OutcomeOK,
+	}
+	switch {
+	case res.Skipped:
+		e.Outcome, e.Error = audit.OutcomeSkipped, res.Err.Error()
+	case res.Err != nil:
+		e.Outcome, e.Error = audit.OutcomeError, res.Err.Error()
+	}
+	if err := r.Audit.Record(e); err != nil && res.Err == nil {
+		res.Err = fmt.Errorf("%s removed but not audited: %w", res.Path, err)
+	}
+	return res
+}
+
+// act carries out r.Strategy on it and fills in what happened.
+func (r Remover) act(it scanner.FileItem, res *Result) {
+	// Space held by other hard links stays allocated when this path goes.
+	freed := it.Allocated
+	if it.Nlink > 1 {
+		freed = 0
+	}
+	// movedOff credits the space if dst is on another filesystem.
+	movedOff := func(dst string) {
+		if d, err := scanner.StatFile(dst); err == nil && d.ID.Dev != it.ID.Dev {
+			res.Reclaimed = freed
+		}
+	}
+
+	switch r.Strategy {
+	case Unlink:
+		if res.Err = os.Remove(it.Path); res.Err == nil {
+			res.Reclaimed = freed
+		}
+	case Truncate:
+		if res.Err = os.Truncate(it.Path, 0); res.Err == nil {
+			// Truncation frees the inode's blocks whatever its link count.
+			after, _ := scanner.StatFile(it.Path)
+			res.Reclaimed = it.Allocated - after.Allocated
+		}
+	case Gzip, Zstd:
+		if res.Dest, res.Err = compress(it.Path, r.Strategy); res.Err == nil {
+			after, _ := scanner.StatFile(res.Dest)
+			res.Reclaimed = freed - after.Allocated
+		}
+	case Archive:
+		if res.Err = r.Check(); res.Err != nil {
+			return
+		}
+		if res.Dest, res.Err = archive(it.Path, r.ArchiveDir); res.Err == nil {
+			movedOff(res.Dest)
+		}
+	default:
+		var e trash.Entry
+		if e, res.Err = trash.Put(it.Path); res.Err == nil {
+			res.Trashed = &e
+			movedOff(e.FilePath())
+		}
+	}
+}
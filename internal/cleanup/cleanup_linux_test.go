@@ -0,0 +1,44 @@
+package cleanup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "daemon.log")
+	if err := os.WriteFile(path, []byte("still logging"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	res := Remover{Strategy: Unlink}.Remove(path)
+	var open *OpenError
+	if !res.Skipped || !errors.As(res.Err, &open) {
+		t.Fatalf("Remove = %+v, want skipped with *OpenError", res)
+	}
+	if len(open.Procs) == 0 || open.Procs[0].PID != os.Getpid() {
+		t.Errorf("holders = %v, want pid %d", open.Procs, os.Getpid())
+	}
+
+	if res := (Remover{Strategy: Truncate}).Remove(path); res.Err != nil {
+		t.Fatalf("truncate: %v", res.Err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Fatalf("after truncate: %v, %v", info, err)
+	}
+
+	if res := (Remover{Strategy: Unlink, Force: true}).Remove(path); res.Err != nil {
+		t.Fatalf("forced remove: %v", res.Err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("file still exists after forced remove: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package cleanup
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/natemollica-nm/topn/internal/audit"
+	"github.com/natemollica-nm/topn/internal/trash"
+)
+
+// Batch is a group of files trashed together, which Undo restores together.
+type Batch struct {
+	Time    time.Time     `json:"time"`
+	Entries []trash.Entry `json:"entries"`
+}
+
+// History persists trashed batches as JSON lines, newest last, so the most
+// recent removal can be undone after topn restarts.
+type History struct {
+	path string
+}
+
+// DefaultHistoryPath returns the history file in topn's state directory.
+func DefaultHistoryPath() (string, error) {
+	dir, err := audit.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "batches.jsonl"), nil
+}
+
+// OpenHistory returns the history stored at path. The file is created on
+// first use.
+func OpenHistory(path string) *History {
+	return &History{path: path}
+}
+
+// Push appends b. Empty batches and a nil History are ignored.
+func (h *History) Push(b Batch) error {
+	if h == nil || len(b.Entries) == 0 {
+		return nil
+	}
+	line, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (h *History) load() ([]Batch, error) {
+	f, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var batches []Batch
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var b Batch
+		if err := json.Unmarshal(sc.Bytes(), &b); err != nil {
+			return nil, fmt.Errorf("%s: %w", h.path, err)
+		}
+		batches = append(batches, b)
+	}
+	return batches, sc.Err()
+}
+
+// save atomically replaces the history with batches.
+func (h *History) save(batches []Batch) error {
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, b := range batches {
+		if err := enc.Encode(b); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// ErrNothingToUndo is returned by Undo when the history is empty.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// RestoreResult is the outcome of restoring one file.
+type RestoreResult struct {
+	Path string
+	// Conflict is set when a file now exists at Path; the trashed copy is
+	// kept so the restore can be retried once the conflict is resolved.
+	Conflict bool
+	// Gone is set when the trashed copy no longer exists, for instance
+	// because the trash was emptied. It cannot be restored.
+	Gone bool
+	Err  error
+}
+
+// Undo restores the most recent batch to its original locations, recording
+// each restore in log. Files that conflict with a file now at their path
+// stay in the batch so that a later Undo can retry them; other failures
+// are dropped, so a batch that can never be restored does not hide the
+// ones before it.
+func (h *History) Undo(log *audit.Log) ([]RestoreResult, error) {
+	if h == nil {
+		return nil, ErrNothingToUndo
+	}
+	batches, err := h.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	last := batches[len(batches)-1]
+	var remaining []trash.Entry
+	results := make([]RestoreResult, 0, len(last.Entries))
+	for _, e := range last.Entries {
+		err := trash.Restore(e)
+		r := RestoreResult{Path: e.Original, Conflict: errors.Is(err, trash.ErrConflict), Err: err}
+		if err != nil && !r.Conflict {
+			_, serr := os.Lstat(e.FilePath())
+			r.Gone = errors.Is(serr, fs.ErrNotExist)
+		}
+		results = append(results, r)
+
+		entry := audit.Entry{Path: e.Original, Strategy: "restore", Outcome: audit.OutcomeOK}
+		if err != nil {
+			if r.Conflict {
+				remaining = append(remaining, e)
+			}
+			entry.Outcome, entry.Error = audit.OutcomeError, err.Error()
+		} else if it, serr := os.Lstat(e.Original); serr == nil {
+			entry.Size, entry.ModTime = it.Size(), it.ModTime()
+		}
+		log.Record(entry)
+	}
+
+	batches = batches[:len(batches)-1]
+	if len(remaining) > 0 {
+		last.Entries = remaining
+		batches = append(batches, last)
+	}
+	return results, h.save(batches)
+}
@@ -0,0 +1,115 @@
+package cleanup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+func TestHistoryUndo(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+
+	dir := filepath.Join(home, "files")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(dir, "a.log")
+	b := filepath.Join(dir, "b.log")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte(p), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := OpenHistory(filepath.Join(home, "state", "batches.jsonl"))
+	results, err := Remover{Strategy: Trash}.RemoveAll([]scanner.FileItem{{Path: a}, {Path: b}}, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Err != nil || r.Trashed == nil {
+			t.Fatalf("remove %s: %+v", r.Path, r)
+		}
+	}
+
+	// Recreate one of the originals so its restore conflicts.
+	if err := os.WriteFile(b, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh History reads the batch back from disk.
+	h = OpenHistory(h.path)
+	restored, err := h.Undo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 2 {
+		t.Fatalf("got %d results, want 2", len(restored))
+	}
+	for _, r := range restored {
+		switch r.Path {
+		case a:
+			if r.Err != nil {
+				t.Errorf("restore %s: %v", a, r.Err)
+			}
+		case b:
+			if !r.Conflict {
+				t.Errorf("restore %s: want conflict, got %v", b, r.Err)
+			}
+		}
+	}
+	if data, err := os.ReadFile(a); err != nil || string(data) != a {
+		t.Errorf("%s not restored: %q, %v", a, data, err)
+	}
+
+	// The conflicting file stays undoable once the conflict is cleared.
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	restored, err = h.Undo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 1 || restored[0].Path != b || restored[0].Err != nil {
+		t.Fatalf("second undo: %+v", restored)
+	}
+	if _, err := h.Undo(nil); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("third undo: got %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestHistoryUndoEmptiedTrash(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+
+	path := filepath.Join(home, "big.log")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h := OpenHistory(filepath.Join(home, "state", "batches.jsonl"))
+	results, err := Remover{Strategy: Trash}.RemoveAll([]scanner.FileItem{{Path: path}}, h)
+	if err != nil || results[0].Trashed == nil {
+		t.Fatalf("trash: %+v, %v", results, err)
+	}
+	// Empty the trash behind the history's back.
+	if err := os.Remove(results[0].Trashed.FilePath()); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := h.Undo(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 1 || !restored[0].Gone {
+		t.Fatalf("undo = %+v, want the file reported gone", restored)
+	}
+	if _, err := h.Undo(nil); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("second undo: got %v, want the gone entry dropped", err)
+	}
+}
@@ -0,0 +1,194 @@
+package cleanup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+const planVersion = 1
+
+// Plan is a reviewed list of removals that can be executed later with
+// topn apply. Each entry records what the file looked like when the plan was
+// made so that Apply can refuse to touch files that have changed since.
+type Plan struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Strategy Strategy  `json:"strategy"`
+	// TotalBytes is the apparent size of all entries. ReclaimableBytes only
+	// counts allocated space of files with a single link, since removing
+	// one link of a hard-linked file frees nothing.
+	TotalBytes       int64       `json:"total_bytes"`
+	ReclaimableBytes int64       `json:"reclaimable_bytes"`
+	Entries          []PlanEntry `json:"entries"`
+}
+
+// PlanEntry is one file in a Plan.
+type PlanEntry struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	Allocated int64     `json:"allocated"`
+	ModTime   time.Time `json:"mtime"`
+	Dev       uint64    `json:"dev"`
+	Ino       uint64    `json:"ino"`
+	Nlink     uint64    `json:"nlink"`
+}
+
+// NewPlan stats paths and builds a plan to remove them with strategy s.
+// Paths that cannot be stat'ed are returned as errors and left out.
+func NewPlan(paths []string, s Strategy) (Plan, []error) {
+	p := Plan{Version: planVersion, Created: time.Now(), Strategy: s}
+	var errs []error
+	for _, path := range paths {
+		it, err := scanner.StatFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		e := PlanEntry{
+			Path:      path,
+			Size:      it.Apparent,
+			Allocated: it.Allocated,
+			ModTime:   it.Time,
+			Dev:       it.ID.Dev,
+			Ino:       it.ID.Ino,
+			Nlink:     it.Nlink,
+		}
+		p.Entries = append(p.Entries, e)
+		p.TotalBytes += e.Size
+		if e.Nlink <= 1 {
+			p.ReclaimableBytes += e.Allocated
+		}
+	}
+	return p, errs
+}
+
+// LoadPlan reads a plan written by WriteJSON.
+func LoadPlan(path string) (Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Plan{}, err
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Plan{}, fmt.Errorf("parse plan %s: %w", path, err)
+	}
+	if p.Version != planVersion {
+		return Plan{}, fmt.Errorf("plan %s has version %d, want %d", path, p.Version, planVersion)
+	}
+	switch p.Strategy {
+	case Trash, Unlink:
+	default:
+		return Plan{}, fmt.Errorf("plan %s has unknown strategy %q", path, p.Strategy)
+	}
+	return p, nil
+}
+
+// WriteFile saves the plan to path, as a shell script if path ends in .sh
+// and as JSON otherwise.
+func (p Plan) WriteFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".sh") {
+		err = p.WriteScript(f)
+		if err == nil {
+			err = f.Chmod(0o755)
+		}
+	} else {
+		err = p.WriteJSON(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (p Plan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WriteScript renders the plan as a POSIX shell script for review. Each
+// removal is guarded by a size check so files that grew or shrank since the
+// plan was made are skipped.
+func (p Plan) WriteScript(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# topn removal plan created %s\n", p.Created.Format(time.RFC3339))
+	fmt.Fprintf(&b, "# strategy: %s, %d files, %s total, %s reclaimable\n",
+		p.Strategy, len(p.Entries), utils.HumanSize(p.TotalBytes), utils.HumanSize(p.ReclaimableBytes))
+	b.WriteString("# Review before running. `topn apply` on the JSON plan also checks mtime and inode.\n")
+	b.WriteString("set -u\n\n")
+	b.WriteString("unchanged() {\n")
+	b.WriteString("\t[ -f \"$1\" ] && [ ! -L \"$1\" ] && [ \"$(wc -c < \"$1\" | tr -d ' ')\" = \"$2\" ] && return 0\n")
+	b.WriteString("\techo \"skipping changed or missing file: $1\" >&2\n")
+	b.WriteString("\treturn 1\n")
+	b.WriteString("}\n\n")
+	if p.Strategy == Trash {
+		b.WriteString("remove() {\n")
+		b.WriteString("\tif command -v gio >/dev/null 2>&1; then gio trash -- \"$1\"\n")
+		b.WriteString("\telif command -v trash-put >/dev/null 2>&1; then trash-put -- \"$1\"\n")
+		b.WriteString("\telse echo \"no trash tool (gio, trash-put) found for: $1\" >&2; return 1\n")
+		b.WriteString("\tfi\n")
+		b.WriteString("}\n\n")
+	} else {
+		b.WriteString("remove() {\n\trm -f -- \"$1\"\n}\n\n")
+	}
+	for _, e := range p.Entries {
+		q := shellQuote(e.Path)
+		fmt.Fprintf(&b, "unchanged %s %d && remove %s\n", q, e.Size, q)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Verify reports an error if the file at e.Path is no longer the file the
+// plan was made for.
+func (e PlanEntry) Verify() error {
+	it, err := scanner.StatFile(e.Path)
+	if err != nil {
+		return err
+	}
+	switch {
+	case it.ID.Dev != e.Dev || it.ID.Ino != e.Ino:
+		return fmt.Errorf("%s: replaced by a different file since the plan was made", e.Path)
+	case it.Apparent != e.Size:
+		return fmt.Errorf("%s: size changed from %d to %d bytes", e.Path, e.Size, it.Apparent)
+	case !it.Time.Equal(e.ModTime):
+		return fmt.Errorf("%s: modified at %s", e.Path, it.Time.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Apply executes p with r, using the plan's strategy. Entries that fail
+// Verify are skipped, as are those r refuses, such as protected files.
+func (r Remover) Apply(p Plan) []Result {
+	r.Strategy = p.Strategy
+	paths := make([]string, len(p.Entries))
+	for i, e := range p.Entries {
+		paths[i] = e.Path
+	}
+	held := r.holders(paths)
+	results := make([]Result, 0, len(p.Entries))
+	for _, e := range p.Entries {
+		if err := e.Verify(); err != nil {
+			results = append(results, r.Skip(e.Path, err))
+			continue
+		}
+		results = append(results, r.remove(e.Path, held))
+	}
+	return results
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
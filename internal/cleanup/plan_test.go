@@ -0,0 +1,91 @@
+package cleanup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+func TestPlanApply(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "changed")
+	gone := filepath.Join(dir, "same")
+	for _, p := range []string{keep, gone} {
+		if err := os.WriteFile(p, []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, errs := NewPlan([]string{keep, gone, filepath.Join(dir, "missing")}, Unlink)
+	if len(plan.Entries) != 2 || len(errs) != 1 {
+		t.Fatalf("entries=%d errs=%d, want 2 and 1", len(plan.Entries), len(errs))
+	}
+	if plan.TotalBytes != 8 {
+		t.Errorf("TotalBytes = %d, want 8", plan.TotalBytes)
+	}
+
+	path := filepath.Join(dir, "plan.json")
+	if err := plan.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(keep, []byte("more data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	results := Remover{}.Apply(loaded)
+	if !results[0].Skipped || results[1].Skipped || results[1].Err != nil {
+		t.Fatalf("results = %+v, want first skipped and second removed", results)
+	}
+	if _, err := os.Lstat(keep); err != nil {
+		t.Errorf("changed file was removed: %v", err)
+	}
+	if _, err := os.Lstat(gone); !os.IsNotExist(err) {
+		t.Errorf("unchanged file still exists: %v", err)
+	}
+}
+
+func TestPlanScript(t *testing.T) {
+	p := Plan{Strategy: Unlink, Entries: []PlanEntry{{Path: "/tmp/it's here", Size: 3}}}
+	var b strings.Builder
+	if err := p.WriteScript(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := `unchanged '/tmp/it'\''s here' 3 && remove '/tmp/it'\''s here'`; !strings.Contains(b.String(), want) {
+		t.Errorf("script missing %q:\n%s", want, b.String())
+	}
+}
+
+func TestRemoveAllSkipsReplaced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.log")
+	if err := os.WriteFile(path, []byte("scanned"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	scanned, err := scanner.StatFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replace the file; keep the old inode alive so the new one differs.
+	if err := os.Rename(path, path+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, _ := Remover{Strategy: Unlink, Force: true}.RemoveAll([]scanner.FileItem{scanned}, nil)
+	var re *ReplacedError
+	if len(results) != 1 || !results[0].Skipped || !errors.As(results[0].Err, &re) {
+		t.Fatalf("RemoveAll = %+v, want skipped with *ReplacedError", results)
+	}
+	if _, err := os.Lstat(path); err != nil {
+		t.Errorf("replacement was removed: %v", err)
+	}
+}
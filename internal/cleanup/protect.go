@@ -0,0 +1,87 @@
+package cleanup
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Protect lists paths that may be shown but never acted on. A pattern
+// protects a path if it matches the path or any of its parent directories,
+// either as a filepath.Match glob or literally, so "/var/lib/postgresql"
+// and "/srv/vm/*.qcow2" both work. Patterns without a slash also match
+// bare names, like "*.qcow2".
+type Protect []string
+
+// Abs resolves the relative patterns that contain a slash, such as
+// "data/db" or "./vm", against the working directory. Left relative they
+// would never match the absolute paths a scan produces.
+func (p Protect) Abs() (Protect, error) {
+	out := make(Protect, len(p))
+	for i, pat := range p {
+		out[i] = pat
+		if filepath.IsAbs(pat) || !strings.ContainsRune(pat, filepath.Separator) {
+			continue
+		}
+		abs, err := filepath.Abs(pat)
+		if err != nil {
+			return nil, fmt.Errorf("protect pattern %q: %v", pat, err)
+		}
+		out[i] = abs
+	}
+	return out, nil
+}
+
+// Match returns the first pattern protecting path. A path that goes
+// through a symlinked directory is also checked at the real location of
+// that directory, which is where removing it acts.
+func (p Protect) Match(path string) (string, bool) {
+	if len(p) == 0 {
+		return "", false
+	}
+	path = filepath.Clean(path)
+	if pat, ok := p.match(path); ok {
+		return pat, true
+	}
+	dir := filepath.Dir(path)
+	if real, err := filepath.EvalSymlinks(dir); err == nil && real != dir {
+		return p.match(filepath.Join(real, filepath.Base(path)))
+	}
+	return "", false
+}
+
+func (p Protect) match(path string) (string, bool) {
+	for _, pat := range p {
+		if pat == "" {
+			continue
+		}
+		pat = filepath.Clean(pat)
+		for a := path; ; a = filepath.Dir(a) {
+			if a == pat {
+				return pat, true
+			}
+			if ok, _ := filepath.Match(pat, a); ok {
+				return pat, true
+			}
+			if !strings.Contains(pat, string(filepath.Separator)) {
+				if ok, _ := filepath.Match(pat, filepath.Base(a)); ok {
+					return pat, true
+				}
+			}
+			if parent := filepath.Dir(a); parent == a {
+				break
+			}
+		}
+	}
+	return "", false
+}
+
+// ProtectedError reports a file that was skipped because it is protected.
+type ProtectedError struct {
+	Path    string
+	Pattern string
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("%s is protected by %q", e.Path, e.Pattern)
+}
@@ -0,0 +1,99 @@
+package cleanup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProtectMatch(t *testing.T) {
+	p := Protect{"/var/lib/postgresql", "/srv/vm/*.qcow2", "*.vmdk", "/home/*/keep"}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/var/lib/postgresql", "/var/lib/postgresql"},
+		{"/var/lib/postgresql/16/main/base/1", "/var/lib/postgresql"},
+		{"/var/lib/postgresql-old/x", ""},
+		{"/srv/vm/win.qcow2", "/srv/vm/*.qcow2"},
+		{"/srv/vm/sub/win.qcow2", ""},
+		{"/data/images/disk.vmdk", "*.vmdk"},
+		{"/home/ana/keep/big.iso", "/home/*/keep"},
+		{"/home/ana/tmp/big.iso", ""},
+	}
+	for _, tt := range tests {
+		got, ok := p.Match(tt.path)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Match(%q) = %q, %v, want %q", tt.path, got, ok, tt.want)
+		}
+	}
+}
+
+func TestRemoveProtected(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db", "base.dat")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("rows"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res := Remover{Strategy: Unlink, Force: true, Protect: Protect{filepath.Join(dir, "db")}}.Remove(path)
+	var pe *ProtectedError
+	if !res.Skipped || !errors.As(res.Err, &pe) {
+		t.Fatalf("Remove = %+v, want skipped with *ProtectedError", res)
+	}
+	if _, err := os.Lstat(path); err != nil {
+		t.Errorf("protected file was removed: %v", err)
+	}
+}
+
+func TestRemoveProtectedThroughSymlinkedDir(t *testing.T) {
+	dir := t.TempDir()
+	protected := filepath.Join(dir, "protected")
+	if err := os.MkdirAll(protected, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(protected, "base.dat")
+	if err := os.WriteFile(real, []byte("rows"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	farm := filepath.Join(dir, "farm")
+	if err := os.MkdirAll(farm, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(protected, filepath.Join(farm, "db")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+
+	p := Protect{protected}
+	if real, err := filepath.EvalSymlinks(protected); err == nil {
+		p = append(p, real)
+	}
+	res := Remover{Strategy: Unlink, Force: true, Protect: p}.Remove(filepath.Join(farm, "db", "base.dat"))
+	var pe *ProtectedError
+	if !res.Skipped || !errors.As(res.Err, &pe) {
+		t.Fatalf("Remove = %+v, want skipped with *ProtectedError", res)
+	}
+	if _, err := os.Lstat(real); err != nil {
+		t.Errorf("protected file was removed through the symlink: %v", err)
+	}
+}
+
+func TestProtectAbs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Protect{"data/db", "./vm", "vm", "*.qcow2", "/srv/keep"}.Abs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Protect{filepath.Join(wd, "data/db"), filepath.Join(wd, "vm"), "vm", "*.qcow2", "/srv/keep"}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Abs = %q, want %q", p, want)
+	}
+}
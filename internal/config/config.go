@@ -0,0 +1,165 @@
+// Package config loads topn's configuration file, which sets defaults for
+// the command-line options and defines named profiles of them.
+//
+// The file is TOML. Top-level keys apply to every run, and each
+// [profiles.NAME] table is selected with -profile NAME. Keys are the flag
+// names without the dash:
+//
+//	min = "1G"
+//	exclude = ["*.git", "node_modules"]
+//
+//	[profiles.ci-runner]
+//	dir = "/var/lib/docker"
+//	top = 20
+//	protect = ["/var/lib/docker/volumes"]
+//	format = "json"
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Settings maps option names to their values. Scalars are stored as a
+// single value and arrays as one value per element.
+type Settings map[string][]string
+
+// Keys returns the option names in s, sorted.
+func (s Settings) Keys() []string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// File is a parsed configuration file.
+type File struct {
+	Path     string
+	Defaults Settings
+	Profiles map[string]Settings
+}
+
+// DefaultPath returns $XDG_CONFIG_HOME/topn/config.toml, falling back to
+// ~/.config when XDG_CONFIG_HOME is unset.
+func DefaultPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "topn", "config.toml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "topn", "config.toml"), nil
+}
+
+// Load reads the configuration at path. A missing file is reported with an
+// error satisfying errors.Is(err, fs.ErrNotExist).
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	f := &File{Path: path, Profiles: map[string]Settings{}}
+	profiles, hasProfiles := doc["profiles"]
+	delete(doc, "profiles")
+	if f.Defaults, err = settings(doc, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if hasProfiles {
+		tables, ok := profiles.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: profiles must be a table; profiles go in [profiles.NAME]", path)
+		}
+		for name, t := range tables {
+			table, ok := t.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: profiles.%s must be a table", path, name)
+			}
+			if f.Profiles[name], err = settings(table, "profiles."+name); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return f, nil
+}
+
+// settings converts a decoded table into Settings. Nested tables are
+// rejected, since options are never tables; table names the table in
+// errors, "" for the top level.
+func settings(table map[string]any, name string) (Settings, error) {
+	s := Settings{}
+	for key, v := range table {
+		switch v := v.(type) {
+		case map[string]any:
+			if name == "" {
+				return nil, fmt.Errorf("unknown table [%s]; profiles go in [profiles.NAME]", key)
+			}
+			return nil, fmt.Errorf("unknown table [%s.%s]", name, key)
+		case []any:
+			vals := make([]string, 0, len(v))
+			for _, e := range v {
+				str, err := scalar(e)
+				if err != nil {
+					return nil, fmt.Errorf("option %s: %v", key, err)
+				}
+				vals = append(vals, str)
+			}
+			s[key] = vals
+		default:
+			str, err := scalar(v)
+			if err != nil {
+				return nil, fmt.Errorf("option %s: %v", key, err)
+			}
+			s[key] = []string{str}
+		}
+	}
+	return s, nil
+}
+
+// scalar formats a decoded value the way it would be given as a flag.
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("unsupported value %v; use a string, number, boolean or array of those", v)
+}
+
+// ErrNoProfile is returned by Profile for names the file does not define.
+var ErrNoProfile = errors.New("no such profile")
+
+// Profile returns the settings of the named profile.
+func (f *File) Profile(name string) (Settings, error) {
+	if p, ok := f.Profiles[name]; ok {
+		return p, nil
+	}
+	names := make([]string, 0, len(f.Profiles))
+	for n := range f.Profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w %q: %s defines none", ErrNoProfile, name, f.Path)
+	}
+	return nil, fmt.Errorf("%w %q in %s (have %s)", ErrNoProfile, name, f.Path, strings.Join(names, ", "))
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `# topn defaults
+min = "500M"
+top = 1_000
+exclude = [
+  "*.git",   # version control
+  'C:\no\escapes',
+]
+
+[profiles]
+
+[profiles.ci-runner]
+dir = "/var/lib/docker"
+xdev = true
+protect = ["/var/lib/docker/volumes"]
+format = "json"
+
+[profiles."home videos"]
+exclude = []
+min = "caf\u00e9\t1"
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(sample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Settings{
+		"min":     {"500M"},
+		"top":     {"1000"},
+		"exclude": {"*.git", `C:\no\escapes`},
+	}
+	if !reflect.DeepEqual(f.Defaults, want) {
+		t.Errorf("Defaults = %v, want %v", f.Defaults, want)
+	}
+
+	ci, err := f.Profile("ci-runner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Settings{
+		"dir":     {"/var/lib/docker"},
+		"xdev":    {"true"},
+		"protect": {"/var/lib/docker/volumes"},
+		"format":  {"json"},
+	}
+	if !reflect.DeepEqual(ci, want) {
+		t.Errorf("ci-runner = %v, want %v", ci, want)
+	}
+
+	videos, err := f.Profile("home videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := videos["exclude"]; got == nil || len(got) != 0 {
+		t.Errorf("empty array = %#v, want empty non-nil", got)
+	}
+	if got := videos["min"][0]; got != "café\t1" {
+		t.Errorf("escaped string = %q", got)
+	}
+
+	if _, err := f.Profile("nope"); !errors.Is(err, ErrNoProfile) || !strings.Contains(err.Error(), "ci-runner, home videos") {
+		t.Errorf("missing profile error = %v", err)
+	}
+}
+
+func load(t *testing.T, src string) (*File, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoadTOMLForms(t *testing.T) {
+	f, err := load(t, "profiles.ci.min = \"1G\"\nprofiles.vm = {top = 5}\nexclude = \"\"\"\nmulti\"\"\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Profiles["ci"]["min"]; !reflect.DeepEqual(got, []string{"1G"}) {
+		t.Errorf("dotted key = %v", got)
+	}
+	if got := f.Profiles["vm"]["top"]; !reflect.DeepEqual(got, []string{"5"}) {
+		t.Errorf("inline table = %v", got)
+	}
+	if got := f.Defaults["exclude"]; !reflect.DeepEqual(got, []string{"multi"}) {
+		t.Errorf("multi-line string = %q", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"bare string":   "min = 1G\n",
+		"duplicate key": "min = \"1G\"\nmin = \"2G\"\n",
+		"unknown table": "[defaults]\nmin = \"1G\"\n",
+		"nested table":  "[profiles.ci.extra]\nmin = \"1G\"\n",
+		"date":          "min = 2024-01-01\n",
+		"unterminated":  "exclude = [\"a\"\n",
+		"array table":   "[[profiles]]\n",
+	}
+	for name, src := range tests {
+		if _, err := load(t, src); err == nil {
+			t.Errorf("%s: loaded %q without error", name, src)
+		}
+	}
+
+	_, err := load(t, "top = 1\n\nmin = oops\n")
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error %v, want it to name line 3", err)
+	}
+}
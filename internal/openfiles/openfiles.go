@@ -0,0 +1,27 @@
+// Package openfiles finds processes that hold files open. Removing such a
+// file frees no space until every holder closes it.
+package openfiles
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Process is a process holding a file open.
+type Process struct {
+	PID  int
+	Comm string
+}
+
+func (p Process) String() string {
+	return fmt.Sprintf("pid %d (%s)", p.PID, p.Comm)
+}
+
+// Describe lists procs as "pid 1 (a), pid 2 (b)".
+func Describe(procs []Process) string {
+	parts := make([]string, len(procs))
+	for i, p := range procs {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,81 @@
+package openfiles
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+type fileID struct{ dev, ino uint64 }
+
+func statID(path string) (fileID, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return fileID{}, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{uint64(st.Dev), uint64(st.Ino)}, true
+}
+
+// Holders reports the processes with a file descriptor open on each of
+// paths, keyed by path. Files are matched by device and inode, so holders
+// are found whatever name they opened the file by. Processes whose fd table
+// cannot be read, typically those of other users, are not reported.
+func Holders(paths []string) (map[string][]Process, error) {
+	want := make(map[fileID][]string)
+	for _, p := range paths {
+		if id, ok := statID(p); ok {
+			want[id] = append(want[id], p)
+		}
+	}
+	if len(want) == 0 {
+		return nil, nil
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	held := make(map[string][]Process)
+	for _, d := range procs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", d.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		seen := make(map[fileID]bool)
+		for _, fd := range fds {
+			id, ok := statID(filepath.Join(fdDir, fd.Name()))
+			if !ok || seen[id] || want[id] == nil {
+				continue
+			}
+			seen[id] = true
+			proc := Process{PID: pid, Comm: comm(pid)}
+			for _, p := range want[id] {
+				held[p] = append(held[p], proc)
+			}
+		}
+	}
+	for _, list := range held {
+		sort.Slice(list, func(i, j int) bool { return list[i].PID < list[j].PID })
+	}
+	return held, nil
+}
+
+func comm(pid int) string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return "?"
+	}
+	return strings.TrimSpace(string(data))
+}
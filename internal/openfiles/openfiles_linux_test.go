@@ -0,0 +1,46 @@
+package openfiles
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHolders(t *testing.T) {
+	dir := t.TempDir()
+	open := filepath.Join(dir, "open.log")
+	closed := filepath.Join(dir, "closed.log")
+	link := filepath.Join(dir, "link.log")
+	for _, p := range []string{open, closed} {
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(open, link); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	held, err := Holders([]string{open, closed, link})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{open, link} {
+		var found bool
+		for _, proc := range held[p] {
+			if proc.PID == os.Getpid() {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: want pid %d among holders, got %v", p, os.Getpid(), held[p])
+		}
+	}
+	if len(held[closed]) != 0 {
+		t.Errorf("%s: want no holders, got %v", closed, held[closed])
+	}
+}
@@ -0,0 +1,104 @@
+package preview
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+// gzipScanLimit bounds how much of a compressed tar is read to list its
+// first members, since skipping a member means decompressing it.
+const gzipScanLimit = 64 << 20
+
+// member formats one archive entry. The name comes from the archive, so it
+// is sanitized.
+func member(name string, size int64, dir bool) string {
+	if dir {
+		return fmt.Sprintf("%9s  %s", "", sanitize(name))
+	}
+	return fmt.Sprintf("%9s  %s", utils.HumanSize(size), sanitize(name))
+}
+
+// tarMembers lists up to n members of the tar archive in f. The reader
+// seeks over member data, so this is cheap even for large archives.
+func tarMembers(f *os.File, n int) ([]string, bool, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	return listTar(tar.NewReader(f), n)
+}
+
+// isTarGz reports whether the gzip stream in f holds a tar archive.
+func isTarGz(f *os.File) bool {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return false
+	}
+	block := make([]byte, 512)
+	if _, err := io.ReadFull(zr, block); err != nil {
+		return false
+	}
+	mime, _ := detect(block)
+	return mime == "application/x-tar"
+}
+
+// tarGzMembers lists up to n members of the gzip-compressed tar archive in
+// f, reading at most gzipScanLimit compressed bytes.
+func tarGzMembers(f *os.File, n int) ([]string, bool, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	zr, err := gzip.NewReader(io.LimitReader(f, gzipScanLimit))
+	if err != nil {
+		return nil, false, err
+	}
+	lines, more, err := listTar(tar.NewReader(zr), n)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// The limit was reached before the end of the archive.
+		return lines, true, nil
+	}
+	return lines, more, err
+}
+
+func listTar(tr *tar.Reader, n int) ([]string, bool, error) {
+	var lines []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return lines, false, nil
+		}
+		if err != nil {
+			return lines, false, err
+		}
+		if len(lines) == n {
+			return lines, true, nil
+		}
+		lines = append(lines, member(hdr.Name, hdr.Size, hdr.Typeflag == tar.TypeDir))
+	}
+}
+
+// zipMembers lists up to n members of the zip archive in f, from its
+// central directory at the end of the file.
+func zipMembers(f *os.File, size int64, n int) ([]string, bool, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, false, err
+	}
+	var lines []string
+	for i, zf := range zr.File {
+		if i == n {
+			break
+		}
+		lines = append(lines, member(zf.Name, int64(zf.UncompressedSize64), zf.FileInfo().IsDir()))
+	}
+	return lines, len(zr.File) > n, nil
+}
@@ -0,0 +1,173 @@
+package preview
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+// noteLimit bounds how much of a core dump's note segment is read.
+const noteLimit = 4 << 20
+
+// elfHeader summarizes an ELF file. For a core dump it adds the process
+// that dumped it and the signal that killed it, when the notes say.
+func elfHeader(f *os.File) ([]string, error) {
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		return nil, err
+	}
+	bits := "32-bit"
+	if ef.Class == elf.ELFCLASS64 {
+		bits = "64-bit"
+	}
+	h := []string{
+		"Machine: " + strings.TrimPrefix(ef.Machine.String(), "EM_") + ", " + bits,
+	}
+	if ef.Type != elf.ET_CORE {
+		if ef.Type == elf.ET_EXEC || ef.Type == elf.ET_DYN {
+			h = append(h, fmt.Sprintf("Entry: %#x", ef.Entry))
+		}
+		return h, nil
+	}
+
+	var mem int64
+	var loads int
+	for _, p := range ef.Progs {
+		switch p.Type {
+		case elf.PT_LOAD:
+			loads++
+			mem += int64(p.Memsz)
+		case elf.PT_NOTE:
+			data, err := io.ReadAll(io.LimitReader(p.Open(), noteLimit))
+			if err != nil {
+				return h, err
+			}
+			h = append(h, coreNotes(data, ef.ByteOrder, ef.Class)...)
+		}
+	}
+	h = append(h, fmt.Sprintf("Memory: %s in %d segments", utils.HumanSize(mem), loads))
+	return h, nil
+}
+
+// coreNotes reads the process name, arguments, pid and signal from the
+// NT_PRPSINFO and NT_PRSTATUS notes of a Linux core dump. Only the 64-bit
+// layouts are decoded.
+func coreNotes(data []byte, bo binary.ByteOrder, class elf.Class) []string {
+	if class != elf.ELFCLASS64 {
+		return nil
+	}
+	var h []string
+	for len(data) >= 12 {
+		namesz, descsz, typ := bo.Uint32(data), bo.Uint32(data[4:]), bo.Uint32(data[8:])
+		descStart := 12 + align4(namesz)
+		descEnd := descStart + int(descsz)
+		if namesz > noteLimit || descsz > noteLimit || descEnd > len(data) {
+			break
+		}
+		desc := data[descStart:descEnd]
+		data = data[descStart+align4(descsz):]
+
+		switch elf.NType(typ) {
+		case elf.NT_PRPSINFO:
+			if len(desc) < 136 {
+				continue
+			}
+			h = append(h,
+				fmt.Sprintf("Process: %s (pid %d)", cstring(desc[40:56]), bo.Uint32(desc[24:])),
+				"Command: "+cstring(desc[56:136]))
+		case elf.NT_PRSTATUS:
+			// Only the first thread's status, which is the one that
+			// received the signal.
+			if len(desc) < 14 || hasPrefix(h, "Signal") {
+				continue
+			}
+			if sig := bo.Uint16(desc[12:]); sig != 0 {
+				h = append(h, fmt.Sprintf("Signal: %d (%s)", sig, syscall.Signal(sig)))
+			}
+		}
+	}
+	return h
+}
+
+func hasPrefix(lines []string, prefix string) bool {
+	for _, l := range lines {
+		if strings.HasPrefix(l, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func align4(n uint32) int {
+	return int((n + 3) &^ 3)
+}
+
+// cstring returns b up to its first NUL, sanitized and without surrounding
+// spaces.
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(sanitize(string(b)))
+}
+
+// qcow2Header summarizes the header of a qcow2 image. All fields are
+// big-endian.
+func qcow2Header(f *os.File) []string {
+	b := make([]byte, 36)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		return nil
+	}
+	be := binary.BigEndian
+	h := []string{
+		fmt.Sprintf("Version: %d", be.Uint32(b[4:])),
+		"Virtual size: " + utils.HumanSize(int64(be.Uint64(b[24:]))),
+	}
+	if bits := be.Uint32(b[20:]); bits < 32 {
+		h = append(h, "Cluster size: "+utils.HumanSize(int64(1)<<bits))
+	}
+	if be.Uint32(b[32:]) != 0 {
+		h = append(h, "Encrypted: yes")
+	}
+	if off, n := be.Uint64(b[8:]), be.Uint32(b[16:]); off != 0 && n > 0 && n < 1024 {
+		name := make([]byte, n)
+		if _, err := f.ReadAt(name, int64(off)); err == nil {
+			h = append(h, "Backing file: "+cstring(name))
+		}
+	}
+	return h
+}
+
+// isoHeader summarizes the primary volume descriptor of an ISO 9660 image,
+// found at 32 KiB.
+func isoHeader(head []byte) []string {
+	const pvd = 0x8000
+	if len(head) < pvd+830 || head[pvd] != 1 {
+		return nil
+	}
+	d := head[pvd:]
+	le := binary.LittleEndian
+	h := []string{
+		"Volume: " + cstring(d[40:72]),
+		"Size: " + utils.HumanSize(int64(le.Uint32(d[80:]))*int64(le.Uint16(d[128:]))),
+	}
+	if sys := cstring(d[8:40]); sys != "" {
+		h = append(h, "System: "+sys)
+	}
+	if app := cstring(d[574:702]); app != "" {
+		h = append(h, "Application: "+app)
+	}
+	// Dates are "YYYYMMDDHHMMSScc" plus a time zone byte, all zeros when
+	// unset. Anything but digits is not a date.
+	if c := string(d[813:827]); strings.Trim(c, "0") != "" && strings.Trim(c, "0123456789") == "" {
+		h = append(h, fmt.Sprintf("Created: %s-%s-%s %s:%s:%s", c[0:4], c[4:6], c[6:8], c[8:10], c[10:12], c[12:14]))
+	}
+	return h
+}
@@ -0,0 +1,217 @@
+// Package preview identifies files by their content and summarizes what is
+// in them, so a large file can be recognized before it is removed.
+package preview
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+// sniffSize is how much of a file is read to detect its type. It reaches
+// past the ISO 9660 primary volume descriptor at 32 KiB.
+const sniffSize = 64 << 10
+
+// hexBytes is how much of a binary file the hexdump shows.
+const hexBytes = 256
+
+// Preview describes a file.
+type Preview struct {
+	Mode       os.FileMode
+	ModTime    time.Time
+	AccessTime time.Time
+	// MIME is detected from the leading bytes, never from the name.
+	MIME string
+	// Kind names the format when it is recognized, e.g. "qcow2 disk image".
+	Kind string
+	// Header lists facts from the header of a recognized format, such as
+	// the virtual size of a disk image.
+	Header []string
+	// Lines is the content: the first lines of a text file, the members of
+	// an archive or a hexdump of the start of anything else. More is set
+	// when Lines stops before the end.
+	Lines []string
+	More  bool
+}
+
+// Inspect describes the file at path, showing at most lines lines of
+// content. It does not follow a final symlink for the metadata, but does
+// for the content.
+func Inspect(path string, lines int) (Preview, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Preview{}, err
+	}
+	p := Preview{Mode: info.Mode(), ModTime: info.ModTime()}
+	p.AccessTime, _ = scanner.Times(info)
+	switch {
+	case info.IsDir():
+		p.MIME = "inode/directory"
+		return p, nil
+	case info.Mode()&os.ModeSymlink != 0:
+		if info, err = os.Stat(path); err != nil {
+			p.MIME = "inode/symlink"
+			return p, nil
+		}
+	}
+	if !info.Mode().IsRegular() {
+		p.MIME = "inode/x-special"
+		return p, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return p, err
+	}
+	defer f.Close()
+	head := make([]byte, sniffSize)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return p, err
+	}
+	head, err = head[:n], nil
+
+	p.MIME, p.Kind = detect(head)
+	switch p.MIME {
+	case "application/x-tar":
+		p.Lines, p.More, err = tarMembers(f, lines)
+	case "application/zip":
+		p.Lines, p.More, err = zipMembers(f, info.Size(), lines)
+	case "application/gzip":
+		if isTarGz(f) {
+			p.Kind = "gzip-compressed tar archive"
+			p.Lines, p.More, err = tarGzMembers(f, lines)
+		}
+	case "application/x-coredump", "application/x-executable", "application/x-sharedlib", "application/x-object":
+		p.Header, err = elfHeader(f)
+	case "application/x-qemu-disk":
+		p.Header = qcow2Header(f)
+	case "application/x-iso9660-image":
+		p.Header = isoHeader(head)
+	}
+	if err != nil {
+		p.Header = append(p.Header, "Error: "+err.Error())
+	}
+	if p.Lines == nil {
+		if strings.HasPrefix(p.MIME, "text/") {
+			p.Lines, p.More = textLines(head, int64(n) < info.Size(), lines)
+		} else {
+			p.Lines, p.More = hexdump(head, lines)
+		}
+	}
+	return p, nil
+}
+
+// magic is a signature at a fixed offset.
+type magic struct {
+	offset int
+	sig    string
+	mime   string
+	kind   string
+}
+
+// magics are checked in order before falling back to http.DetectContentType,
+// which does not know disk images or compressed streams other than gzip.
+var magics = []magic{
+	{0, "QFI\xfb", "application/x-qemu-disk", "qcow2 disk image"},
+	{0x8001, "CD001", "application/x-iso9660-image", "ISO 9660 image"},
+	{257, "ustar", "application/x-tar", "tar archive"},
+	{0, "PK\x03\x04", "application/zip", "zip archive"},
+	{0, "PK\x05\x06", "application/zip", "empty zip archive"},
+	{0, "\x1f\x8b", "application/gzip", "gzip-compressed data"},
+	{0, "\x28\xb5\x2f\xfd", "application/zstd", "zstd-compressed data"},
+	{0, "\xfd7zXZ\x00", "application/x-xz", "xz-compressed data"},
+	{0, "BZh", "application/x-bzip2", "bzip2-compressed data"},
+	{0, "7z\xbc\xaf\x27\x1c", "application/x-7z-compressed", "7-zip archive"},
+	{0, "SQLite format 3\x00", "application/vnd.sqlite3", "SQLite database"},
+	{0, "KDMV", "application/x-vmdk", "VMDK disk image"},
+	{0, "conectix", "application/x-vhd", "VHD disk image"},
+}
+
+// elfTypes maps the ELF e_type field to a MIME type and description.
+var elfTypes = map[byte][2]string{
+	1: {"application/x-object", "ELF relocatable object"},
+	2: {"application/x-executable", "ELF executable"},
+	3: {"application/x-sharedlib", "ELF shared object"},
+	4: {"application/x-coredump", "ELF core dump"},
+}
+
+// detect returns the MIME type of a file starting with head, and a
+// description when the format is recognized.
+func detect(head []byte) (mime, kind string) {
+	if len(head) == 0 {
+		return "application/x-empty", "empty file"
+	}
+	for _, m := range magics {
+		if bytes.HasPrefix(head[min(m.offset, len(head)):], []byte(m.sig)) {
+			return m.mime, m.kind
+		}
+	}
+	if len(head) > 17 && bytes.HasPrefix(head, []byte("\x7fELF")) {
+		// e_type is a half word at 16, in the byte order given at 5.
+		t := head[16]
+		if head[5] == 2 {
+			t = head[17]
+		}
+		if e, ok := elfTypes[t]; ok {
+			return e[0], e[1]
+		}
+		return "application/x-elf", "ELF file"
+	}
+	return http.DetectContentType(head), ""
+}
+
+// textLines splits the start of a text file into at most n sanitized lines.
+func textLines(head []byte, truncated bool, n int) ([]string, bool) {
+	if truncated {
+		// Drop a partial last line, including any split rune.
+		if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+			head = head[:i+1]
+		}
+	}
+	text := strings.TrimSuffix(string(head), "\n")
+	all := strings.Split(text, "\n")
+	more := truncated
+	if len(all) > n {
+		all, more = all[:n], true
+	}
+	lines := make([]string, len(all))
+	for i, l := range all {
+		lines[i] = sanitize(l)
+	}
+	return lines, more
+}
+
+// sanitize makes a string read from a file safe to print: tabs become
+// spaces and other control characters are dropped so they cannot move the
+// terminal cursor.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r == utf8.RuneError, unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// hexdump formats the start of head like hexdump -C, in at most n lines.
+func hexdump(head []byte, n int) ([]string, bool) {
+	size := min(len(head), hexBytes, n*16)
+	if size == 0 {
+		return nil, false
+	}
+	lines := strings.Split(strings.TrimSuffix(hex.Dump(head[:size]), "\n"), "\n")
+	return lines, size < len(head)
+}
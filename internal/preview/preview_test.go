@@ -0,0 +1,208 @@
+package preview
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func inspect(t *testing.T, path string) Preview {
+	t.Helper()
+	p, err := Inspect(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestInspectText(t *testing.T) {
+	p := inspect(t, writeFile(t, "notes", []byte("one\ttwo\nthree\x1b[2J\nfour\nfive\n")))
+	if !strings.HasPrefix(p.MIME, "text/plain") {
+		t.Errorf("MIME = %q", p.MIME)
+	}
+	if want := []string{"one two", "three[2J", "four"}; !reflect.DeepEqual(p.Lines, want) || !p.More {
+		t.Errorf("Lines = %q (more %v), want %q and more", p.Lines, p.More, want)
+	}
+}
+
+func TestInspectSanitizesArchiveNames(t *testing.T) {
+	p := inspect(t, writeFile(t, "evil.tar", tarball(t, "a\x1b[2Jb\tc")))
+	if len(p.Lines) != 1 || !strings.HasSuffix(p.Lines[0], "a[2Jb c") {
+		t.Errorf("Lines = %q, want the escape dropped and the tab a space", p.Lines)
+	}
+}
+
+func TestInspectBinary(t *testing.T) {
+	p := inspect(t, writeFile(t, "blob", bytes.Repeat([]byte{0, 1, 2, 0xff}, 100)))
+	if p.MIME != "application/octet-stream" || len(p.Lines) != 3 || !p.More {
+		t.Fatalf("got %q with %d lines (more %v)", p.MIME, len(p.Lines), p.More)
+	}
+	if !strings.HasPrefix(p.Lines[0], "00000000  00 01 02 ff") {
+		t.Errorf("hexdump starts %q", p.Lines[0])
+	}
+}
+
+func tarball(t *testing.T, names ...string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, n := range names {
+		data := []byte(strings.Repeat("x", 2048))
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0o644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInspectArchives(t *testing.T) {
+	tb := tarball(t, "a", "b", "c", "d")
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(tb)
+	zw.Close()
+	var zb bytes.Buffer
+	w := zip.NewWriter(&zb)
+	for _, n := range []string{"x/", "x/y"} {
+		if _, err := w.Create(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	tests := []struct {
+		name, mime string
+		data       []byte
+		members    []string
+		more       bool
+	}{
+		{"t.tar", "application/x-tar", tb, []string{"a", "b", "c"}, true},
+		{"t.tgz", "application/gzip", gz.Bytes(), []string{"a", "b", "c"}, true},
+		{"t.zip", "application/zip", zb.Bytes(), []string{"x/", "x/y"}, false},
+	}
+	for _, tt := range tests {
+		p := inspect(t, writeFile(t, tt.name, tt.data))
+		var names []string
+		for _, l := range p.Lines {
+			names = append(names, strings.TrimSpace(l[9:]))
+		}
+		if p.MIME != tt.mime || !reflect.DeepEqual(names, tt.members) || p.More != tt.more {
+			t.Errorf("%s: %s %q (more %v), want %s %q (more %v)", tt.name, p.MIME, names, p.More, tt.mime, tt.members, tt.more)
+		}
+	}
+}
+
+func TestInspectQcow2(t *testing.T) {
+	b := make([]byte, 512)
+	be := binary.BigEndian
+	copy(b, "QFI\xfb")
+	be.PutUint32(b[4:], 3)
+	be.PutUint64(b[8:], 200)
+	be.PutUint32(b[16:], 8)
+	be.PutUint32(b[20:], 16)
+	be.PutUint64(b[24:], 10<<30)
+	copy(b[200:], "base.img")
+
+	p := inspect(t, writeFile(t, "vm.qcow2", b))
+	want := []string{"Version: 3", "Virtual size: 10.0G", "Cluster size: 64.0K", "Backing file: base.img"}
+	if p.Kind != "qcow2 disk image" || !reflect.DeepEqual(p.Header, want) {
+		t.Errorf("got %q %q, want %q", p.Kind, p.Header, want)
+	}
+}
+
+func TestInspectISO(t *testing.T) {
+	b := make([]byte, 0x8000+2048)
+	d := b[0x8000:]
+	d[0] = 1
+	copy(d[1:], "CD001")
+	copy(d[8:], "LINUX"+strings.Repeat(" ", 27))
+	copy(d[40:], "UBUNTU_24"+strings.Repeat(" ", 23))
+	binary.LittleEndian.PutUint32(d[80:], 1024)
+	binary.LittleEndian.PutUint16(d[128:], 2048)
+	copy(d[813:], "2024042512300000")
+
+	p := inspect(t, writeFile(t, "x.iso", b))
+	want := []string{"Volume: UBUNTU_24", "Size: 2.0M", "System: LINUX", "Created: 2024-04-25 12:30:00"}
+	if p.MIME != "application/x-iso9660-image" || !reflect.DeepEqual(p.Header, want) {
+		t.Errorf("got %q %q, want %q", p.MIME, p.Header, want)
+	}
+}
+
+// note encodes an ELF note with the "CORE" owner.
+func note(typ uint32, desc []byte) []byte {
+	le := binary.LittleEndian
+	b := make([]byte, 20)
+	le.PutUint32(b, 5)
+	le.PutUint32(b[4:], uint32(len(desc)))
+	le.PutUint32(b[8:], typ)
+	copy(b[12:], "CORE")
+	return append(b, desc...)
+}
+
+func TestInspectCore(t *testing.T) {
+	le := binary.LittleEndian
+	psinfo := make([]byte, 136)
+	le.PutUint32(psinfo[24:], 4242)
+	copy(psinfo[40:], "postgres")
+	copy(psinfo[56:], "postgres -D /data ")
+	status := make([]byte, 336)
+	le.PutUint16(status[12:], 11)
+	notes := append(note(1, status), note(3, psinfo)...)
+
+	h := make([]byte, 64+56)
+	copy(h, "\x7fELF\x02\x01\x01")
+	le.PutUint16(h[16:], 4)  // ET_CORE
+	le.PutUint16(h[18:], 62) // EM_X86_64
+	le.PutUint32(h[20:], 1)
+	le.PutUint64(h[32:], 64)
+	le.PutUint16(h[52:], 64)
+	le.PutUint16(h[54:], 56)
+	le.PutUint16(h[56:], 1)
+	le.PutUint16(h[58:], 64)
+	ph := h[64:]
+	le.PutUint32(ph, 4) // PT_NOTE
+	le.PutUint64(ph[8:], uint64(len(h)))
+	le.PutUint64(ph[32:], uint64(len(notes)))
+
+	p := inspect(t, writeFile(t, "core", append(h, notes...)))
+	if p.MIME != "application/x-coredump" {
+		t.Fatalf("MIME = %q", p.MIME)
+	}
+	want := []string{
+		"Machine: X86_64, 64-bit",
+		"Signal: 11 (segmentation fault)",
+		"Process: postgres (pid 4242)",
+		"Command: postgres -D /data",
+		"Memory: 0B in 0 segments",
+	}
+	if !reflect.DeepEqual(p.Header, want) {
+		t.Errorf("Header = %q, want %q", p.Header, want)
+	}
+}
+
+func TestInspectDir(t *testing.T) {
+	if p := inspect(t, t.TempDir()); p.MIME != "inode/directory" || !p.Mode.IsDir() {
+		t.Errorf("got %q %v", p.MIME, p.Mode)
+	}
+}
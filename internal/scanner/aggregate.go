@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects what a scan ranks.
+type Mode int
+
+const (
+	// ByFile ranks individual regular files.
+	ByFile Mode = iota
+	// ByDir ranks directories by the cumulative size of the files below them.
+	ByDir
+)
+
+// ParseMode parses the -by flag value.
+func ParseMode(s string) (Mode, bool) {
+	switch s {
+	case "file", "files":
+		return ByFile, true
+	case "dir", "dirs", "directory":
+		return ByDir, true
+	}
+	return ByFile, false
+}
+
+func (m Mode) String() string {
+	if m == ByDir {
+		return "dir"
+	}
+	return "file"
+}
+
+// dirTotals rolls file sizes up into their ancestor directories below the
+// file's root, stopping depth levels down. Files deeper than depth count
+// towards their ancestor at that depth. A depth of zero means no limit.
+type dirTotals struct {
+	mu    sync.Mutex
+	depth int
+	sums  map[string]*FileItem
+}
+
+func newDirTotals(depth int) *dirTotals {
+	return &dirTotals{depth: depth, sums: make(map[string]*FileItem)}
+}
+
+func (d *dirTotals) add(f FileItem) {
+	rel, err := filepath.Rel(f.Root, filepath.Dir(f.Path))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dir := f.Root
+	for i, part := range strings.Split(rel, string(filepath.Separator)) {
+		if d.depth > 0 && i >= d.depth {
+			break
+		}
+		dir = filepath.Join(dir, part)
+		it, ok := d.sums[dir]
+		if !ok {
+			it = &FileItem{Path: dir, Root: f.Root, IsDir: true}
+			d.sums[dir] = it
+		}
+		it.Size += f.Size
+		it.Apparent += f.Apparent
+		it.Allocated += f.Allocated
+		it.Files++
+		if f.Time.After(it.Time) {
+			it.Time = f.Time
+		}
+	}
+}
+
+// top returns the n largest directories holding at least min bytes, largest
+// first, along with how many directories met the threshold.
+func (d *dirTotals) top(n int, min int64) ([]FileItem, int64) {
+	d.mu.Lock()
+	h := &minHeap{}
+	var kept int64
+	for _, it := range d.sums {
+		if it.Size >= min {
+			keepTopN(h, *it, n)
+			kept++
+		}
+	}
+	d.mu.Unlock()
+
+	items := make([]FileItem, h.Len())
+	copy(items, *h)
+	sortBySize(items)
+	return items, kept
+}
+
+// keptByRoot counts the directories holding at least min bytes per root.
+func (d *dirTotals) keptByRoot(min int64) map[string]int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	kept := make(map[string]int64)
+	for _, it := range d.sums {
+		if it.Size >= min {
+			kept[it.Root]++
+		}
+	}
+	return kept
+}
@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"syscall"
+)
+
+const defaultMaxErrors = 1000
+
+// ErrorKind classifies a walk or stat failure.
+type ErrorKind int
+
+const (
+	ErrPermission ErrorKind = iota
+	ErrNotExist
+	ErrIO
+	ErrOther
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrPermission:
+		return "permission denied"
+	case ErrNotExist:
+		return "not found"
+	case ErrIO:
+		return "I/O error"
+	default:
+		return "other"
+	}
+}
+
+// ScanError records a single failure encountered during a scan.
+type ScanError struct {
+	Path  string
+	Op    string
+	Errno syscall.Errno
+	Kind  ErrorKind
+	Err   error
+}
+
+func (e ScanError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Path, e.Err)
+}
+
+// ErrorCounts tallies scan errors by kind. Counts include errors that were
+// not kept in Stats.Errors because the record limit was reached.
+type ErrorCounts struct {
+	Permission int64
+	NotExist   int64
+	IO         int64
+	Other      int64
+}
+
+func (c ErrorCounts) Total() int64 {
+	return c.Permission + c.NotExist + c.IO + c.Other
+}
+
+// errorLog collects scan errors from the walker and workers, keeping at most
+// max records.
+type errorLog struct {
+	mu      sync.Mutex
+	max     int
+	counts  ErrorCounts
+	records []ScanError
+}
+
+func (l *errorLog) record(path, op string, err error) {
+	e := ScanError{Path: path, Op: op, Err: err}
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		e.Path, e.Op, e.Err = pe.Path, pe.Op, pe.Err
+	}
+	errors.As(err, &e.Errno)
+
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		e.Kind = ErrPermission
+	case errors.Is(err, fs.ErrNotExist):
+		e.Kind = ErrNotExist
+	case e.Errno == syscall.EIO:
+		e.Kind = ErrIO
+	default:
+		e.Kind = ErrOther
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch e.Kind {
+	case ErrPermission:
+		l.counts.Permission++
+	case ErrNotExist:
+		l.counts.NotExist++
+	case ErrIO:
+		l.counts.IO++
+	default:
+		l.counts.Other++
+	}
+	if len(l.records) < l.max {
+		l.records = append(l.records, e)
+	}
+}
@@ -0,0 +1,249 @@
+package scanner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileNames are the per-directory ignore files honored when
+// Config.IgnoreFiles is set. Rules in later files take precedence.
+var IgnoreFileNames = []string{".gitignore", ".topnignore"}
+
+// Rule is one compiled exclude pattern with gitignore semantics:
+//
+//   - a trailing "/" only matches directories;
+//   - a leading "!" re-includes what earlier rules excluded, except below an
+//     excluded directory, which is never entered;
+//   - "*" and "?" do not match "/", while a "**" segment matches any number
+//     of directories;
+//   - a pattern containing a "/" other than a trailing one is anchored to
+//     the directory of its ignore file, otherwise it matches a name at any
+//     depth below it.
+//
+// Patterns given with -exclude are anchored to the filesystem root when
+// they start with "/" and match at any depth otherwise.
+type Rule struct {
+	Pattern string
+	// Source is "-exclude" or the ignore file the rule came from, and Line
+	// its line number there.
+	Source  string
+	Line    int
+	Negate  bool
+	DirOnly bool
+
+	base string
+	segs []string
+}
+
+func (r Rule) String() string {
+	if r.Line > 0 {
+		return fmt.Sprintf("%q (%s:%d)", r.Pattern, r.Source, r.Line)
+	}
+	return fmt.Sprintf("%q (%s)", r.Pattern, r.Source)
+}
+
+// parseRule compiles one gitignore line relative to base. It returns false
+// for blank lines and comments.
+func parseRule(line, base, source string, lineNo int, anchorSlash bool) (Rule, bool) {
+	p := strings.TrimRight(line, " \t\r")
+	if p == "" || p[0] == '#' {
+		return Rule{}, false
+	}
+	r := Rule{Pattern: strings.TrimSpace(line), Source: source, Line: lineNo, base: base}
+	switch {
+	case p[0] == '!':
+		r.Negate = true
+		p = p[1:]
+	case strings.HasPrefix(p, `\!`), strings.HasPrefix(p, `\#`):
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		r.DirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return Rule{}, false
+	}
+
+	anchored := strings.HasPrefix(p, "/")
+	if anchorSlash {
+		anchored = strings.Contains(p, "/")
+	}
+	r.segs = strings.Split(strings.TrimPrefix(p, "/"), "/")
+	if !anchored {
+		r.segs = append([]string{"**"}, r.segs...)
+	}
+	return r, true
+}
+
+// matches reports whether r applies to p, an absolute slash-separated path.
+func (r Rule) matches(p string, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+	rel, ok := strings.CutPrefix(p, r.base)
+	if !ok || (rel != "" && rel[0] != '/' && !strings.HasSuffix(r.base, "/")) {
+		return false
+	}
+	rel = strings.Trim(rel, "/")
+	if rel == "" {
+		return false
+	}
+	return matchSegments(r.segs, strings.Split(rel, "/"))
+}
+
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			if len(rest) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// lastMatch returns the last rule matching p, which decides whether p is
+// excluded, or nil if no rule matches.
+func lastMatch(rules []Rule, p string, isDir bool) *Rule {
+	p = filepath.ToSlash(p)
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(p, isDir) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func excludedBy(r *Rule) bool { return r != nil && !r.Negate }
+
+// excludes holds the -exclude patterns, compiled into rules.
+type excludes struct {
+	rules []Rule
+}
+
+func newExcludes(globs []string) excludes {
+	var e excludes
+	for _, g := range globs {
+		if r, ok := parseRule(g, "/", "-exclude", 0, false); ok {
+			e.rules = append(e.rules, r)
+		}
+	}
+	return e
+}
+
+// readIgnoreFiles compiles the ignore files in dir, with rules anchored at
+// shown, the name dir is reported under.
+func readIgnoreFiles(dir, shown string) ([]Rule, error) {
+	var rules []Rule
+	for _, name := range IgnoreFileNames {
+		file := filepath.Join(dir, name)
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return rules, err
+		}
+		sc := bufio.NewScanner(f)
+		for n := 1; sc.Scan(); n++ {
+			if r, ok := parseRule(sc.Text(), filepath.ToSlash(shown), filepath.Join(shown, name), n, true); ok {
+				rules = append(rules, r)
+			}
+		}
+		err = sc.Err()
+		f.Close()
+		if err != nil {
+			return rules, err
+		}
+	}
+	return rules, nil
+}
+
+// ignoreFrame is the rule set in effect inside dir: the -exclude rules plus
+// those of every ignore file from the root down to dir.
+type ignoreFrame struct {
+	dir   string
+	rules []Rule
+}
+
+// Verdict explains why a path is or is not excluded.
+type Verdict struct {
+	Excluded bool
+	// Rule is the deciding rule, nil when no rule matched.
+	Rule *Rule
+	// Via is the excluded parent directory when Path is excluded because
+	// the walk never enters it, or "" when the rule matched Path itself.
+	Via string
+}
+
+// Explain reports whether the scan would exclude path, and which rule
+// decided it, applying the -exclude patterns and, if enabled, the ignore
+// files between the scan root containing path and path itself.
+func Explain(config Config, p string) (Verdict, error) {
+	p, err := filepath.Abs(p)
+	if err != nil {
+		return Verdict{}, err
+	}
+	ex := newExcludes(config.Excludes)
+
+	root := string(filepath.Separator)
+	for _, r := range config.ScanRoots() {
+		r, err := filepath.Abs(r)
+		if err == nil && within(p, r) && len(r) > len(root) {
+			root = r
+		}
+	}
+
+	rules := ex.rules
+	load := func(dir string) error {
+		if !config.IgnoreFiles {
+			return nil
+		}
+		more, err := readIgnoreFiles(dir, dir)
+		rules = append(rules[:len(rules):len(rules)], more...)
+		return err
+	}
+	if err := load(root); err != nil {
+		return Verdict{}, err
+	}
+
+	rel, _ := filepath.Rel(root, p)
+	if rel == "." {
+		return Verdict{}, nil
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	dir := root
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		if r := lastMatch(rules, dir, true); excludedBy(r) {
+			return Verdict{Excluded: true, Rule: r, Via: dir}, nil
+		}
+		if err := load(dir); err != nil {
+			return Verdict{}, err
+		}
+	}
+
+	info, err := os.Lstat(p)
+	isDir := err == nil && info.IsDir()
+	r := lastMatch(rules, p, isDir)
+	return Verdict{Excluded: excludedBy(r), Rule: r}, nil
+}
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestRuleSemantics(t *testing.T) {
+	compile := func(lines ...string) []Rule {
+		var rules []Rule
+		for i, l := range lines {
+			if r, ok := parseRule(l, "/repo", ".gitignore", i+1, true); ok {
+				rules = append(rules, r)
+			}
+		}
+		return rules
+	}
+	tests := []struct {
+		rules []string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{[]string{"tmp"}, "/repo/attempts", false, false},
+		{[]string{"tmp"}, "/repo/a/tmp", true, true},
+		{[]string{"build/"}, "/repo/build", false, false},
+		{[]string{"build/"}, "/repo/src/build", true, true},
+		{[]string{"/out"}, "/repo/out", true, true},
+		{[]string{"/out"}, "/repo/src/out", true, false},
+		{[]string{"doc/*.pdf"}, "/repo/doc/a.pdf", false, true},
+		{[]string{"doc/*.pdf"}, "/repo/doc/x/a.pdf", false, false},
+		{[]string{"doc/*.pdf"}, "/repo/x/doc/a.pdf", false, false},
+		{[]string{"**/logs/*.gz"}, "/repo/a/b/logs/x.gz", false, true},
+		{[]string{"cache/**"}, "/repo/cache/a/b", false, true},
+		{[]string{"cache/**"}, "/repo/cache", true, false},
+		{[]string{"a/**/z"}, "/repo/a/z", false, true},
+		{[]string{"a/**/z"}, "/repo/a/b/c/z", false, true},
+		{[]string{"*.iso", "!keep.iso"}, "/repo/keep.iso", false, false},
+		{[]string{"*.iso", "!keep.iso"}, "/repo/drop.iso", false, true},
+		{[]string{"# comment", `\#hash`}, "/repo/#hash", false, true},
+		{[]string{"*.iso"}, "/other/x.iso", false, false},
+	}
+	for _, tt := range tests {
+		got := excludedBy(lastMatch(compile(tt.rules...), tt.path, tt.isDir))
+		if got != tt.want {
+			t.Errorf("%q on %s (dir=%v) = %v, want %v", tt.rules, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestScanIgnoreFiles(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, data string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("proj/.gitignore", "target/\n*.o\n")
+	write("proj/.topnignore", "!keep.o\n")
+	write("proj/target/app", "x")
+	write("proj/a.o", "x")
+	write("proj/keep.o", "x")
+	write("proj/src/main.go", "x")
+	write("other/a.o", "x")
+
+	scan := func(c Config) []string {
+		c.Root, c.TopN, c.Workers = root, 100, 2
+		results, _ := New(c).Scan()
+		var paths []string
+		for _, it := range results {
+			if rel, _ := filepath.Rel(root, it.Path); filepath.Base(rel)[0] != '.' {
+				paths = append(paths, rel)
+			}
+		}
+		sort.Strings(paths)
+		return paths
+	}
+
+	got := scan(Config{IgnoreFiles: true})
+	want := []string{"other/a.o", "proj/keep.o", "proj/src/main.go"}
+	if len(got) != len(want) {
+		t.Fatalf("with ignore files got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("with ignore files got %v, want %v", got, want)
+		}
+	}
+	if got := scan(Config{}); len(got) != 5 {
+		t.Errorf("without ignore files got %v, want all 5 files", got)
+	}
+
+	c := Config{Root: root, IgnoreFiles: true, Excludes: []string{"src"}}
+	v, err := Explain(c, filepath.Join(root, "proj/target/app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.Excluded || v.Via != filepath.Join(root, "proj/target") || v.Rule.Pattern != "target/" || v.Rule.Line != 1 {
+		t.Errorf("Explain(target/app) = %+v, rule %v", v, v.Rule)
+	}
+	if v, _ := Explain(c, filepath.Join(root, "proj/keep.o")); v.Excluded || v.Rule == nil || !v.Rule.Negate {
+		t.Errorf("Explain(keep.o) = %+v, want re-included by a negation", v)
+	}
+	if v, _ := Explain(c, filepath.Join(root, "proj/src/main.go")); !v.Excluded || v.Rule.Source != "-exclude" {
+		t.Errorf("Explain(src/main.go) = %+v, want excluded by -exclude", v)
+	}
+	if v, _ := Explain(c, filepath.Join(root, "other")); v.Excluded || v.Rule != nil {
+		t.Errorf("Explain(other) = %+v, want no rule", v)
+	}
+}
@@ -0,0 +1,77 @@
+package scanner
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileID identifies a file by device and inode.
+type FileID struct {
+	Dev uint64
+	Ino uint64
+}
+
+// sysStat holds the fields of a stat result that os.FileInfo does not expose.
+type sysStat struct {
+	id        FileID
+	nlink     uint64
+	uid       uint32
+	allocated int64
+	atime     time.Time
+	ctime     time.Time
+}
+
+// linkTracker folds hard links, and files reached through followed symlinks,
+// so each inode is reported once. Paths are only kept for inodes that can
+// be reported; the rest are tracked by key alone.
+type linkTracker struct {
+	mu    sync.Mutex
+	seen  map[FileID]struct{}
+	paths map[FileID][]string
+}
+
+func newLinkTracker() *linkTracker {
+	return &linkTracker{seen: make(map[FileID]struct{}), paths: make(map[FileID][]string)}
+}
+
+// first reports whether path is the first link to id seen during the scan.
+// When keep is set, path is also recorded as one of the inode's links.
+func (l *linkTracker) first(id FileID, path string, keep bool) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, seen := l.seen[id]
+	l.seen[id] = struct{}{}
+	if keep {
+		l.paths[id] = append(l.paths[id], path)
+	}
+	return !seen
+}
+
+// resolve fills in the alternate paths of each item reached more than once. The
+// lexically smallest path becomes the primary one so that results do not
+// depend on worker scheduling.
+func (l *linkTracker) resolve(items []FileItem) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range items {
+		it := &items[i]
+		paths := append([]string(nil), l.paths[it.ID]...)
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		it.Path, it.Links = paths[0], paths[1:]
+	}
+}
+
+// resolveTargets records the real path of items whose path goes through a
+// symlink.
+func resolveTargets(items []FileItem) {
+	for i := range items {
+		if real, err := filepath.EvalSymlinks(items[i].Path); err == nil && real != items[i].Path {
+			items[i].Target = real
+		}
+	}
+}
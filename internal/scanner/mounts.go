@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MountUsage summarises the files found on one mounted filesystem.
+type MountUsage struct {
+	Dev        uint64
+	MountPoint string
+	FSType     string
+	Source     string
+	Bytes      int64
+	Files      int64
+}
+
+// mountInfo describes one entry of the system mount table.
+type mountInfo struct {
+	dev        uint64
+	mountPoint string
+	fsType     string
+	source     string
+}
+
+// mountTally accumulates bytes per device during a scan, remembering one
+// path per device to pick the right entry when a device is mounted twice.
+type mountTally struct {
+	mu     sync.Mutex
+	usage  map[uint64]*MountUsage
+	sample map[uint64]string
+}
+
+func newMountTally() *mountTally {
+	return &mountTally{
+		usage:  make(map[uint64]*MountUsage),
+		sample: make(map[uint64]string),
+	}
+}
+
+func (t *mountTally) add(it FileItem) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.usage[it.ID.Dev]
+	if !ok {
+		u = &MountUsage{Dev: it.ID.Dev}
+		t.usage[it.ID.Dev] = u
+		t.sample[it.ID.Dev] = it.Path
+	}
+	u.Bytes += it.Size
+	u.Files++
+}
+
+// summary resolves devices against the mount table and returns the usage
+// per mount, largest first. Devices missing from the table keep an empty
+// MountPoint. A nil tally yields nil.
+func (t *mountTally) summary() []MountUsage {
+	if t == nil {
+		return nil
+	}
+	table, _ := readMounts()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]MountUsage, 0, len(t.usage))
+	for dev, u := range t.usage {
+		sample := t.sample[dev]
+		best := -1
+		for i, m := range table {
+			if m.dev != dev || !underMount(sample, m.mountPoint) {
+				continue
+			}
+			if best < 0 || len(m.mountPoint) > len(table[best].mountPoint) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			u.MountPoint = table[best].mountPoint
+			u.FSType = table[best].fsType
+			u.Source = table[best].source
+		}
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Bytes > out[j].Bytes })
+	return out
+}
+
+func underMount(path, mountPoint string) bool {
+	if mountPoint == "/" {
+		return true
+	}
+	return path == mountPoint || strings.HasPrefix(path, mountPoint+"/")
+}
@@ -0,0 +1,82 @@
+package scanner
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readMounts parses /proc/self/mountinfo.
+func readMounts() ([]mountInfo, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []mountInfo
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if m, ok := parseMountInfo(sc.Text()); ok {
+			mounts = append(mounts, m)
+		}
+	}
+	return mounts, sc.Err()
+}
+
+// parseMountInfo parses one mountinfo line, e.g.
+//
+//	36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw
+func parseMountInfo(line string) (mountInfo, bool) {
+	pre, post, ok := strings.Cut(line, " - ")
+	if !ok {
+		return mountInfo{}, false
+	}
+	fields := strings.Fields(pre)
+	tail := strings.Fields(post)
+	if len(fields) < 5 || len(tail) < 2 {
+		return mountInfo{}, false
+	}
+	majStr, minStr, ok := strings.Cut(fields[2], ":")
+	if !ok {
+		return mountInfo{}, false
+	}
+	major, err1 := strconv.ParseUint(majStr, 10, 32)
+	minor, err2 := strconv.ParseUint(minStr, 10, 32)
+	if err1 != nil || err2 != nil {
+		return mountInfo{}, false
+	}
+	return mountInfo{
+		dev:        mkdev(major, minor),
+		mountPoint: unescapeMount(fields[4]),
+		fsType:     tail[0],
+		source:     unescapeMount(tail[1]),
+	}, true
+}
+
+// mkdev encodes a device number the way glibc does for st_dev.
+func mkdev(major, minor uint64) uint64 {
+	return (major&0xfffff000)<<32 | (major&0xfff)<<8 |
+		(minor&0xffffff00)<<12 | minor&0xff
+}
+
+// unescapeMount decodes the octal escapes (\040 for space, etc.) the kernel
+// uses in mountinfo paths.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
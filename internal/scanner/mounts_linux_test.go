@@ -0,0 +1,21 @@
+package scanner
+
+import "testing"
+
+func TestParseMountInfo(t *testing.T) {
+	line := `36 35 98:0 / /mnt/my\040disk rw,noatime master:1 - ext4 /dev/sda1 rw,errors=continue`
+	m, ok := parseMountInfo(line)
+	if !ok {
+		t.Fatal("parseMountInfo failed")
+	}
+	if m.mountPoint != "/mnt/my disk" || m.fsType != "ext4" || m.source != "/dev/sda1" {
+		t.Errorf("got %+v", m)
+	}
+	if m.dev != mkdev(98, 0) {
+		t.Errorf("dev = %#x, want %#x", m.dev, mkdev(98, 0))
+	}
+
+	if _, ok := parseMountInfo("garbage"); ok {
+		t.Error("parseMountInfo accepted a malformed line")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package scanner
+
+import "errors"
+
+func readMounts() ([]mountInfo, error) {
+	return nil, errors.New("mount table is only available on Linux")
+}
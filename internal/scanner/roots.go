@@ -0,0 +1,156 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// RootStats is one scan root's share of a scan.
+type RootStats struct {
+	Root      string
+	FilesSeen int64
+	FilesKept int64
+	BytesSeen int64
+	// Nested lists other configured roots that lie inside Root, or resolve
+	// to it, and were scanned as part of it instead of on their own.
+	Nested []string
+}
+
+// ScanRoots returns the roots to scan: Roots if set, else Root.
+func (c Config) ScanRoots() []string {
+	if len(c.Roots) > 0 {
+		return c.Roots
+	}
+	return []string{c.Root}
+}
+
+// rootCounts are the live per-root counters behind RootStats.
+type rootCounts struct {
+	seen, kept, bytes atomic.Int64
+}
+
+// dedupeRoots drops roots that lie inside another root, or name the same
+// directory through a different path or symlink, so that no file is walked
+// twice. The remaining roots keep their order; nested maps each of them to
+// the roots it absorbed. With oneFS a root is only absorbed when the walk of
+// the outer root would reach it without crossing into another filesystem.
+func dedupeRoots(roots []string, oneFS bool) (kept []string, nested map[string][]string) {
+	resolved := make([]string, len(roots))
+	for i, r := range roots {
+		resolved[i] = filepath.Clean(r)
+		if abs, err := filepath.Abs(r); err == nil {
+			resolved[i] = abs
+		}
+		if real, err := filepath.EvalSymlinks(resolved[i]); err == nil {
+			resolved[i] = real
+		}
+	}
+
+	// Visit shallower roots first so each root is absorbed by the
+	// outermost root containing it.
+	order := make([]int, len(roots))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(resolved[order[a]]) < len(resolved[order[b]])
+	})
+
+	outer := make(map[int]int)
+	var accepted []int
+	for _, i := range order {
+		outer[i] = i
+		for _, j := range accepted {
+			if within(resolved[i], resolved[j]) && (!oneFS || sameFilesystem(resolved[i], resolved[j])) {
+				outer[i] = j
+				break
+			}
+		}
+		if outer[i] == i {
+			accepted = append(accepted, i)
+		}
+	}
+
+	nested = make(map[string][]string)
+	for i, r := range roots {
+		if j := outer[i]; j == i {
+			kept = append(kept, r)
+		} else {
+			nested[roots[j]] = append(nested[roots[j]], r)
+		}
+	}
+	return kept, nested
+}
+
+// within reports whether path is dir or lies below it.
+func within(path, dir string) bool {
+	sep := string(filepath.Separator)
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, sep)+sep)
+}
+
+// sameFilesystem reports whether path and every directory between it and
+// dir are on dir's device, so that a walk of dir confined to one filesystem
+// reaches path.
+func sameFilesystem(path, dir string) bool {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return false
+	}
+	dev := statDetails(info).id.Dev
+	for p := path; p != dir; p = filepath.Dir(p) {
+		info, err := os.Stat(p)
+		if err != nil || statDetails(info).id.Dev != dev {
+			return false
+		}
+		if filepath.Dir(p) == p {
+			return false
+		}
+	}
+	return true
+}
+
+// fsUsedTotal sums the used space of the distinct filesystems holding
+// roots. The used space only says how much a root holds when the root is
+// the top of its filesystem, so the total is 0, unknown, unless every root
+// is.
+func fsUsedTotal(roots []string) int64 {
+	var total int64
+	seen := make(map[uint64]bool)
+	for _, r := range roots {
+		info, err := os.Stat(r)
+		if err != nil {
+			continue
+		}
+		if !isMountTop(r) {
+			return 0
+		}
+		dev := statDetails(info).id.Dev
+		if seen[dev] {
+			continue
+		}
+		seen[dev] = true
+		total += fsUsedBytes(r)
+	}
+	return total
+}
+
+// isMountTop reports whether dir is the top directory of its filesystem:
+// the root, or a directory whose parent is on another device.
+func isMountTop(dir string) bool {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = real
+	}
+	parent := filepath.Dir(dir)
+	if parent == dir {
+		return true
+	}
+	di, err1 := os.Stat(dir)
+	pi, err2 := os.Stat(parent)
+	return err1 == nil && err2 == nil && statDetails(di).id.Dev != statDetails(pi).id.Dev
+}
@@ -4,29 +4,107 @@ import (
 	"container/heap"
 	"context"
 	"os"
-	"path/filepath"
 	"sort"
-	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type FileItem struct {
-	Size int64
-	Path string
+	// Size is the apparent or allocated size, per Config.Usage.
+	Size      int64
+	Path      string
+	Apparent  int64
+	Allocated int64
+	// ID and Nlink come from stat. When other links to the same inode were
+	// found during the scan their paths are listed in Links, and the inode
+	// is only reported once.
+	ID    FileID
+	Nlink uint64
+	Links []string
+	// Uid is the owner of the file. It is zero where stat has no owner.
+	Uid uint32
+	// Time is the timestamp selected by Config.TimeField. For directory
+	// totals it is the most recent time of the files below.
+	Time time.Time
+	// Target is the resolved path when Path goes through a followed symlink.
+	Target string
+	// IsDir marks a directory total produced in ByDir mode, where Size is
+	// the cumulative size of the Files regular files below Path.
+	IsDir bool
+	Files int64
+	// Root is the scan root Path was found under.
+	Root string
 }
 
 type Stats struct {
 	FilesSeen int64
 	FilesKept int64
+	// DuplicateLinks counts paths that were folded into an earlier hard
+	// link to the same inode.
+	DuplicateLinks int64
+	// Partial is set when the scan was cancelled before the walk finished.
+	Partial bool
+	// ErrorCounts tallies every walk and stat failure, while Errors holds at
+	// most Config.MaxErrors of them.
+	ErrorCounts ErrorCounts
+	Errors      []ScanError
+	// SkippedMounts lists directories not entered because of OneFileSystem.
+	SkippedMounts []string
+	// SymlinkLoops counts followed directory links that pointed back into
+	// an already walked tree.
+	SymlinkLoops int64
+	// Mounts is the per-mount breakdown when Config.Mounts is set.
+	Mounts []MountUsage
+	// Roots breaks the scan down by root, in the order they were given.
+	Roots []RootStats
+	// Tree holds the directory tree of each root, in the order of Roots,
+	// when Config.Tree is set.
+	Tree []*Dir
 }
 
 type Config struct {
+	// Root is the directory to scan. Roots scans several directories in one
+	// run instead, walking them concurrently into a single ranking; roots
+	// nested inside another are only walked once.
 	Root     string
+	Roots    []string
 	MinBytes int64
 	TopN     int
 	Workers  int
-	Excludes []string
+	// Excludes are gitignore-style patterns; see Rule. With IgnoreFiles,
+	// the .gitignore and .topnignore files found during the walk apply
+	// too, below the directory holding them.
+	Excludes    []string
+	IgnoreFiles bool
+	// Mode selects whether files or directory totals are ranked. In ByDir
+	// mode sizes roll up at most Depth levels below Root (0 for no limit)
+	// and MinBytes applies to the directory totals.
+	Mode  Mode
+	Depth int
+	// Tree keeps the cumulative size of every directory in Stats.Tree, in
+	// either mode and regardless of Depth and TopN.
+	Tree bool
+	// Usage picks the size that MinBytes, ranking and totals are based on.
+	Usage Usage
+	// OlderThan and NewerThan keep only files whose TimeField timestamp is
+	// before or after the given instant. Zero values disable the filter.
+	OlderThan time.Time
+	NewerThan time.Time
+	TimeField TimeField
+	// OneFileSystem stops the walk from descending into directories on a
+	// different device than Root, like find -xdev.
+	OneFileSystem bool
+	// Follow resolves symlinks to files and directories instead of
+	// skipping them. If FollowUnder is set, only links whose target lies
+	// under one of its prefixes are followed.
+	Follow      bool
+	FollowUnder []string
+	// Mounts enables the per-mount breakdown in Stats.Mounts.
+	Mounts bool
+	// MaxErrors bounds the error records kept in Stats.Errors. Zero means
+	// the default of 1000.
+	MaxErrors int
 }
 
 type Scanner struct {
@@ -34,12 +112,39 @@ type Scanner struct {
 	ex     excludes
 }
 
-type ProgressCallback func(current string, progress float64)
+// Progress is a point-in-time snapshot of a running scan.
+type Progress struct {
+	FilesSeen  int64
+	BytesSeen  int64
+	DirsWalked int64
+	CurrentDir string
+	// BytesTotal is an estimate of the bytes under the root, taken from the
+	// used space of its filesystem. It is 0 when unknown, including when a
+	// root is below the top of its filesystem.
+	BytesTotal int64
+	Errors     int64
+	Elapsed    time.Duration
+	Top        []FileItem
+}
+
+// Fraction returns the estimated completion of the scan in [0, 1].
+func (p Progress) Fraction() float64 {
+	if p.BytesTotal <= 0 {
+		return 0
+	}
+	f := float64(p.BytesSeen) / float64(p.BytesTotal)
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+const progressInterval = 100 * time.Millisecond
 
 func New(config Config) *Scanner {
 	return &Scanner{
 		config: config,
-		ex:     excludes{globs: config.Excludes},
+		ex:     newExcludes(config.Excludes),
 	}
 }
 
@@ -47,98 +152,298 @@ func (s *Scanner) Scan() ([]FileItem, Stats) {
 	return s.ScanWithProgress(nil)
 }
 
-func (s *Scanner) ScanWithProgress(callback ProgressCallback) ([]FileItem, Stats) {
+func (s *Scanner) ScanWithProgress(progress chan<- Progress) ([]FileItem, Stats) {
 	ctx := context.Background()
-	return s.ScanWithContext(ctx, callback)
+	return s.ScanWithContext(ctx, progress)
 }
 
-func (s *Scanner) ScanWithContext(ctx context.Context, callback ProgressCallback) ([]FileItem, Stats) {
-	var filesSeen, filesKept atomic.Int64
-	
+// ScanWithContext walks the root until done or ctx is cancelled. If progress
+// is non-nil, snapshots are sent to it periodically without blocking the
+// scan. A final snapshot is delivered before the channel is closed, so the
+// caller must keep receiving until then, unless ctx was cancelled.
+func (s *Scanner) ScanWithContext(ctx context.Context, progress chan<- Progress) ([]FileItem, Stats) {
+	var filesSeen, filesKept, bytesSeen, dirsWalked, dupLinks atomic.Int64
+	roots, nested := dedupeRoots(s.config.ScanRoots(), s.config.OneFileSystem)
+	counts := make([]rootCounts, len(roots))
+	var currentDir atomic.Value
+	currentDir.Store(roots[0])
+	start := time.Now()
+	bytesTotal := fsUsedTotal(roots)
+
 	h := &minHeap{}
 	heap.Init(h)
 	var mu sync.Mutex
-	
+
+	var dirs *dirTotals
+	if s.config.Mode == ByDir {
+		dirs = newDirTotals(s.config.Depth)
+	}
+
+	var dirTree *tree
+	if s.config.Tree {
+		dirTree = newTree(s.config.MinBytes)
+	}
+
+	links := newLinkTracker()
+
+	var mounts *mountTally
+	if s.config.Mounts {
+		mounts = newMountTally()
+	}
+
+	errs := &errorLog{max: s.config.MaxErrors}
+	if errs.max <= 0 {
+		errs.max = defaultMaxErrors
+	}
+
 	var wg sync.WaitGroup
-	pathChan := make(chan string, 1000)
-	
+	pathChan := make(chan job, 1000)
+
 	// Start workers
 	for i := 0; i < s.config.Workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for path := range pathChan {
+			for j := range pathChan {
 				select {
 				case <-ctx.Done():
 					return
 				default:
 				}
-				
+
+				path, rc := j.path, &counts[j.root]
 				filesSeen.Add(1)
-				if callback != nil {
-					callback(path, float64(filesSeen.Load())/1000.0) // Rough progress
+				rc.seen.Add(1)
+
+				stat, op := os.Lstat, "lstat"
+				if s.config.Follow {
+					stat, op = os.Stat, "stat"
+				}
+				info, err := stat(path)
+				if err != nil {
+					errs.record(path, op, err)
+					continue
 				}
-				
-				if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
-					if sz := info.Size(); sz >= s.config.MinBytes {
+				if info.Mode().IsRegular() {
+					st := statDetails(info)
+					size := info.Size()
+					if s.config.Usage == UsageAllocated {
+						size = st.allocated
+					}
+					// Every link to an inode has its size, so only links
+					// that can make the results need their path kept.
+					keep := dirs == nil && size >= s.config.MinBytes
+					if (st.nlink > 1 || s.config.Follow) && !links.first(st.id, path, keep) {
+						dupLinks.Add(1)
+						continue
+					}
+					it := FileItem{
+						Path:      path,
+						Apparent:  info.Size(),
+						Allocated: st.allocated,
+						ID:        st.id,
+						Nlink:     st.nlink,
+						Uid:       st.uid,
+						Time:      info.ModTime(),
+						Root:      roots[j.root],
+					}
+					switch s.config.TimeField {
+					case AccessTime:
+						it.Time = st.atime
+					case ChangeTime:
+						it.Time = st.ctime
+					}
+					it.Size = size
+					bytesSeen.Add(it.Size)
+					rc.bytes.Add(it.Size)
+					if mounts != nil {
+						mounts.add(it)
+					}
+					if !s.config.ageMatch(it.Time) {
+						continue
+					}
+					if dirTree != nil {
+						dirTree.add(it)
+					}
+					if dirs != nil {
+						dirs.add(it)
+					} else if it.Size >= s.config.MinBytes {
 						mu.Lock()
-						keepTopN(h, FileItem{Size: sz, Path: path}, s.config.TopN)
+						keepTopN(h, it, s.config.TopN)
 						mu.Unlock()
 						filesKept.Add(1)
+						rc.kept.Add(1)
 					}
 				}
 			}
 		}()
 	}
-	
-	// Walk filesystem
-	go func() {
-		defer close(pathChan)
-		filepath.WalkDir(s.config.Root, func(path string, d os.DirEntry, err error) error {
-			select {
-			case <-ctx.Done():
-				return filepath.SkipAll
-			default:
-			}
-			
-			if err != nil {
-				return nil
-			}
-			if s.ex.match(path) {
-				if d.IsDir() {
-					return filepath.SkipDir
-				}
-				return nil
-			}
-			if d.Type()&os.ModeSymlink != 0 {
-				return nil
-			}
-			if !d.IsDir() {
+
+	snapshot := func() Progress {
+		var top []FileItem
+		if dirs != nil {
+			top, _ = dirs.top(s.config.TopN, s.config.MinBytes)
+		} else {
+			mu.Lock()
+			top = make([]FileItem, h.Len())
+			copy(top, *h)
+			mu.Unlock()
+			sortBySize(top)
+		}
+		errs.mu.Lock()
+		errCount := errs.counts.Total()
+		errs.mu.Unlock()
+		return Progress{
+			FilesSeen:  filesSeen.Load(),
+			BytesSeen:  bytesSeen.Load(),
+			DirsWalked: dirsWalked.Load(),
+			CurrentDir: currentDir.Load().(string),
+			BytesTotal: bytesTotal,
+			Errors:     errCount,
+			Elapsed:    time.Since(start),
+			Top:        top,
+		}
+	}
+
+	var reporter sync.WaitGroup
+	done := make(chan struct{})
+	if progress != nil {
+		reporter.Add(1)
+		go func() {
+			defer reporter.Done()
+			defer close(progress)
+			ticker := time.NewTicker(progressInterval)
+			defer ticker.Stop()
+			for {
 				select {
-				case pathChan <- path:
-				case <-ctx.Done():
-					return filepath.SkipAll
+				case <-done:
+					// A cancelled scan's reader may have gone away, so
+					// the final snapshot must not block forever.
+					select {
+					case progress <- snapshot():
+					case <-ctx.Done():
+					}
+					return
+				case <-ticker.C:
+					select {
+					case progress <- snapshot():
+					default:
+					}
 				}
 			}
-			return nil
-		})
+		}()
+	}
+
+	// Walk the roots concurrently
+	walkers := make([]*walker, len(roots))
+	var walking sync.WaitGroup
+	for i, root := range roots {
+		w := &walker{
+			ctx:        ctx,
+			config:     s.config,
+			ex:         s.ex,
+			errs:       errs,
+			paths:      pathChan,
+			root:       root,
+			rootIdx:    i,
+			dirsWalked: &dirsWalked,
+			currentDir: &currentDir,
+			visited:    make(map[FileID]bool),
+		}
+		if s.config.OneFileSystem {
+			if info, err := os.Lstat(root); err == nil {
+				w.rootDev = statDetails(info).id.Dev
+			}
+		}
+		walkers[i] = w
+		walking.Add(1)
+		go func() {
+			defer walking.Done()
+			w.walk(w.root, w.root)
+		}()
+	}
+	walkDone := make(chan struct{})
+	go func() {
+		defer close(walkDone)
+		defer close(pathChan)
+		walking.Wait()
 	}()
-	
+
 	wg.Wait()
+	<-walkDone
+	close(done)
+	reporter.Wait()
 
 	// Extract results
 	results := make([]FileItem, h.Len())
 	for i := len(results) - 1; i >= 0; i-- {
 		results[i] = heap.Pop(h).(FileItem)
 	}
-	sort.Slice(results, func(i, j int) bool { return results[i].Size > results[j].Size })
+	links.resolve(results)
+	if s.config.Follow {
+		resolveTargets(results)
+	}
+	sortBySize(results)
+	if dirs != nil {
+		var kept int64
+		results, kept = dirs.top(s.config.TopN, s.config.MinBytes)
+		filesKept.Store(kept)
+		byRoot := dirs.keptByRoot(s.config.MinBytes)
+		for i := range roots {
+			counts[i].kept.Store(byRoot[roots[i]])
+		}
+	}
+
+	var skipped []string
+	var loops int64
+	rootStats := make([]RootStats, len(roots))
+	for i, w := range walkers {
+		skipped = append(skipped, w.skipped...)
+		loops += w.loops
+		rootStats[i] = RootStats{
+			Root:      roots[i],
+			FilesSeen: counts[i].seen.Load(),
+			FilesKept: counts[i].kept.Load(),
+			BytesSeen: counts[i].bytes.Load(),
+			Nested:    nested[roots[i]],
+		}
+	}
+
+	var trees []*Dir
+	if dirTree != nil {
+		trees = dirTree.roots(roots)
+	}
 
 	return results, Stats{
-		FilesSeen: filesSeen.Load(),
-		FilesKept: filesKept.Load(),
+		FilesSeen:      filesSeen.Load(),
+		FilesKept:      filesKept.Load(),
+		DuplicateLinks: dupLinks.Load(),
+		Partial:        ctx.Err() != nil,
+		ErrorCounts:    errs.counts,
+		Errors:         errs.records,
+		SkippedMounts:  skipped,
+		SymlinkLoops:   loops,
+		Mounts:         mounts.summary(),
+		Roots:          rootStats,
+		Tree:           trees,
 	}
 }
 
+// sortBySize orders items largest first, breaking ties on file count and
+// then path so results are stable across runs.
+func sortBySize(items []FileItem) {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Path < b.Path
+	})
+}
+
 type minHeap []FileItem
 
 func (h minHeap) Len() int           { return len(h) }
@@ -164,20 +469,3 @@ func keepTopN(h *minHeap, it FileItem, n int) {
 		heap.Push(h, it)
 	}
 }
-
-type excludes struct{ globs []string }
-
-func (e excludes) match(path string) bool {
-	for _, g := range e.globs {
-		if ok, _ := filepath.Match(g, path); ok {
-			return true
-		}
-		if ok, _ := filepath.Match(g, filepath.Base(path)); ok {
-			return true
-		}
-		if !strings.ContainsAny(g, "*?[]") && strings.Contains(path, g) {
-			return true
-		}
-	}
-	return false
-}
\ No newline at end of file
@@ -1,46 +1,385 @@
 package scanner
 
 import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestExcludes(t *testing.T) {
-	ex := excludes{globs: []string{"*.log", "node_modules", "/tmp/*"}}
-	
+	rules := newExcludes([]string{"*.log", "node_modules", "/tmp/*"}).rules
+
 	tests := []struct {
 		path     string
+		isDir    bool
 		expected bool
 	}{
-		{"/home/user/app.log", true},
-		{"/home/user/data.txt", false},
-		{"/home/user/node_modules/pkg", true},
-		{"/tmp/file", true},
-		{"/var/tmp/file", false},
+		{"/home/user/app.log", false, true},
+		{"/home/user/data.txt", false, false},
+		{"/home/user/node_modules", true, true},
+		{"/tmp/file", false, true},
+		{"/var/tmp/file", false, false},
 	}
-	
+
 	for _, tt := range tests {
-		if got := ex.match(tt.path); got != tt.expected {
-			t.Errorf("match(%q) = %v, want %v", tt.path, got, tt.expected)
+		if got := excludedBy(lastMatch(rules, tt.path, tt.isDir)); got != tt.expected {
+			t.Errorf("lastMatch(%q) excludes = %v, want %v", tt.path, got, tt.expected)
 		}
 	}
 }
 
 func TestKeepTopN(t *testing.T) {
 	h := &minHeap{}
-	
+
 	// Add items
 	keepTopN(h, FileItem{Size: 100, Path: "a"}, 3)
 	keepTopN(h, FileItem{Size: 200, Path: "b"}, 3)
 	keepTopN(h, FileItem{Size: 50, Path: "c"}, 3)
 	keepTopN(h, FileItem{Size: 300, Path: "d"}, 3)
-	
+
 	if h.Len() != 3 {
 		t.Errorf("heap size = %d, want 3", h.Len())
 	}
-	
+
 	// Should contain 100, 200, 300 (not 50)
 	min := (*h)[0].Size
 	if min != 100 {
 		t.Errorf("min size = %d, want 100", min)
 	}
-}
\ No newline at end of file
+}
+func TestScanProgress(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(root, name), make([]byte, 10), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(Config{Root: root, TopN: 2, Workers: 2})
+	ch := make(chan Progress, 1)
+	var last Progress
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for p := range ch {
+			last = p
+		}
+	}()
+	results, stats := s.ScanWithContext(context.Background(), ch)
+	<-done
+
+	if stats.FilesSeen != 3 || len(results) != 2 {
+		t.Fatalf("seen=%d results=%d, want 3 and 2", stats.FilesSeen, len(results))
+	}
+	if last.FilesSeen != 3 || last.BytesSeen != 30 {
+		t.Errorf("final progress = %+v, want 3 files and 30 bytes", last)
+	}
+}
+
+func TestScanCancelled(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a"), make([]byte, 10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, stats := New(Config{Root: root, TopN: 1, Workers: 1}).ScanWithContext(ctx, nil)
+	if !stats.Partial {
+		t.Error("stats.Partial = false after cancellation, want true")
+	}
+}
+
+func TestScanCancelledAbandonedProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// A full channel nobody reads, as left behind by a superseded scan.
+	progress := make(chan Progress, 1)
+	progress <- Progress{}
+
+	done := make(chan struct{})
+	go func() {
+		New(Config{Root: t.TempDir(), TopN: 1, Workers: 1}).ScanWithContext(ctx, progress)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled scan blocked on its final progress snapshot")
+	}
+}
+
+func TestErrorLog(t *testing.T) {
+	l := &errorLog{max: 1}
+	l.record("/a", "walk", &fs.PathError{Op: "open", Path: "/a", Err: syscall.EACCES})
+	l.record("/b", "lstat", &fs.PathError{Op: "lstat", Path: "/b", Err: syscall.ENOENT})
+
+	if l.counts.Permission != 1 || l.counts.NotExist != 1 {
+		t.Errorf("counts = %+v, want one permission and one not-exist", l.counts)
+	}
+	if len(l.records) != 1 {
+		t.Fatalf("records = %d, want 1", len(l.records))
+	}
+	if r := l.records[0]; r.Op != "open" || r.Errno != syscall.EACCES || r.Kind != ErrPermission {
+		t.Errorf("record = %+v, want open/EACCES/permission", r)
+	}
+}
+
+func TestDirTotals(t *testing.T) {
+	d := newDirTotals(1)
+	d.add(FileItem{Root: "/r", Path: "/r/a/b/f1", Size: 100})
+	d.add(FileItem{Root: "/r", Path: "/r/a/f2", Size: 50})
+	d.add(FileItem{Root: "/r", Path: "/r/c/f3", Size: 120})
+	d.add(FileItem{Root: "/r", Path: "/r/f4", Size: 1000})
+
+	top, kept := d.top(10, 0)
+	if kept != 2 || len(top) != 2 {
+		t.Fatalf("kept=%d len=%d, want 2 directories", kept, len(top))
+	}
+	if top[0].Path != "/r/a" || top[0].Size != 150 || top[0].Files != 2 || !top[0].IsDir {
+		t.Errorf("top[0] = %+v, want /r/a with 150 bytes in 2 files", top[0])
+	}
+
+	d = newDirTotals(0)
+	d.add(FileItem{Root: "/r", Path: "/r/a/b/f1", Size: 100})
+	if top, _ := d.top(10, 0); len(top) != 2 {
+		t.Errorf("unlimited depth kept %d directories, want 2", len(top))
+	}
+}
+
+func TestSparse(t *testing.T) {
+	tests := []struct {
+		item FileItem
+		want bool
+	}{
+		{FileItem{Apparent: 1000, Allocated: 100}, true},
+		{FileItem{Apparent: 1000, Allocated: 4096}, false},
+		{FileItem{Apparent: 0, Allocated: 0}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.item.Sparse(); got != tt.want {
+			t.Errorf("%+v.Sparse() = %v, want %v", tt.item, got, tt.want)
+		}
+	}
+}
+
+func TestScanFoldsHardLinks(t *testing.T) {
+	root := t.TempDir()
+	orig := filepath.Join(root, "b", "big")
+	if err := os.MkdirAll(filepath.Dir(orig), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(orig, make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(orig, filepath.Join(root, "a")); err != nil {
+		t.Skipf("hard links unsupported: %v", err)
+	}
+
+	results, stats := New(Config{Root: root, TopN: 10, Workers: 2}).Scan()
+	if len(results) != 1 || stats.FilesKept != 1 || stats.DuplicateLinks != 1 {
+		t.Fatalf("results=%d kept=%d dup=%d, want the inode once", len(results), stats.FilesKept, stats.DuplicateLinks)
+	}
+	if got := results[0]; got.Path != filepath.Join(root, "a") || len(got.Links) != 1 || got.Links[0] != orig {
+		t.Errorf("item = %+v, want primary %q with link %q", got, filepath.Join(root, "a"), orig)
+	}
+}
+
+func TestLinkTrackerKeepsOnlyReportablePaths(t *testing.T) {
+	l := newLinkTracker()
+	small, big := FileID{Dev: 1, Ino: 1}, FileID{Dev: 1, Ino: 2}
+	if !l.first(small, "/s1", false) || l.first(small, "/s2", false) {
+		t.Error("small inode: want only the first link reported as first")
+	}
+	if !l.first(big, "/b1", true) || l.first(big, "/b2", true) {
+		t.Error("big inode: want only the first link reported as first")
+	}
+	if len(l.paths) != 1 || len(l.paths[big]) != 2 {
+		t.Errorf("paths = %v, want only the big inode's two links", l.paths)
+	}
+}
+
+func TestScanMultipleRoots(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int{"var/log/syslog": 300, "var/cache": 200, "opt/app.img": 100}
+	for name, size := range files {
+		path := filepath.Join(base, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, o := filepath.Join(base, "var"), filepath.Join(base, "opt")
+	if err := os.Symlink(o, filepath.Join(base, "opt-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	roots := []string{filepath.Join(v, "log"), v, o, filepath.Join(base, "opt-link")}
+	results, stats := New(Config{Roots: roots, TopN: 10, Workers: 4}).Scan()
+	if len(results) != 3 || stats.FilesSeen != 3 {
+		t.Fatalf("results=%d seen=%d, want each file once", len(results), stats.FilesSeen)
+	}
+	if results[0].Path != filepath.Join(v, "log", "syslog") || results[0].Root != v {
+		t.Errorf("results[0] = %+v, want syslog under %s", results[0], v)
+	}
+
+	if len(stats.Roots) != 2 {
+		t.Fatalf("Roots = %+v, want /var and /opt", stats.Roots)
+	}
+	if r := stats.Roots[0]; r.Root != v || r.FilesSeen != 2 || r.BytesSeen != 500 || len(r.Nested) != 1 {
+		t.Errorf("Roots[0] = %+v, want %s with 2 files, 500 bytes and log nested", r, v)
+	}
+	if r := stats.Roots[1]; r.Root != o || r.FilesKept != 1 || len(r.Nested) != 1 {
+		t.Errorf("Roots[1] = %+v, want %s with opt-link nested", r, o)
+	}
+}
+
+func TestFsUsedTotalNeedsMountTop(t *testing.T) {
+	if !isMountTop("/") {
+		t.Error("isMountTop(/) = false")
+	}
+	dir := t.TempDir()
+	if isMountTop(dir) {
+		t.Skip("temp dir is a mount point")
+	}
+	if total := fsUsedTotal([]string{dir}); total != 0 {
+		t.Errorf("fsUsedTotal(%s) = %d, want 0 below the top of a filesystem", dir, total)
+	}
+}
+
+func TestDedupeRootsOneFileSystem(t *testing.T) {
+	root, proc := "/", "/proc"
+	ri, err1 := os.Stat(root)
+	pi, err2 := os.Stat(proc)
+	if err1 != nil || err2 != nil || statDetails(ri).id.Dev == statDetails(pi).id.Dev {
+		t.Skip("no separate filesystem mounted at /proc")
+	}
+	if kept, _ := dedupeRoots([]string{root, proc}, false); len(kept) != 1 {
+		t.Errorf("kept = %q without -xdev, want /proc absorbed", kept)
+	}
+	if kept, _ := dedupeRoots([]string{root, proc}, true); len(kept) != 2 {
+		t.Errorf("kept = %q with -xdev, want /proc kept as its own root", kept)
+	}
+}
+
+func TestScanTree(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]int{"a/x": 100, "a/b/y": 300, "a/b/z": 50, "c/w": 200, "v": 10}
+	for name, size := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// TopN and Depth bound the ranking, not the tree.
+	_, stats := New(Config{Root: root, TopN: 1, MinBytes: 60, Mode: ByDir, Depth: 1, Workers: 2, Tree: true}).Scan()
+	if len(stats.Tree) != 1 {
+		t.Fatalf("Tree has %d roots, want 1", len(stats.Tree))
+	}
+	top := stats.Tree[0]
+	if top.Path != root || top.Size != 660 || top.Files != 5 {
+		t.Errorf("root = %s %d bytes %d files, want %s 660 bytes 5 files", top.Path, top.Size, top.Files, root)
+	}
+	if size, n := top.Own(); size != 10 || n != 1 {
+		t.Errorf("root Own = %d, %d; want 10, 1", size, n)
+	}
+	if len(top.Dirs) != 2 || top.Dirs[0].Path != filepath.Join(root, "a") || top.Dirs[0].Size != 450 {
+		t.Fatalf("root Dirs = %+v, want a (450) before c", top.Dirs)
+	}
+	b := top.Find(filepath.Join(root, "a", "b"))
+	if b == nil || b.Size != 350 || b.Files != 2 {
+		t.Fatalf("a/b = %+v, want 350 bytes in 2 files", b)
+	}
+	// Only files of at least MinBytes are listed; z is just counted.
+	if len(b.Top) != 1 || b.Top[0].Path != filepath.Join(b.Path, "y") {
+		t.Errorf("a/b Top = %+v, want only y", b.Top)
+	}
+	if top.Find(filepath.Join(root, "nope")) != nil {
+		t.Error("Find returned a directory that does not exist")
+	}
+
+	if _, stats := New(Config{Root: root, TopN: 1, Workers: 2}).Scan(); stats.Tree != nil {
+		t.Error("Tree is set without Config.Tree")
+	}
+}
+
+func TestScanFollowSymlinks(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	vol := filepath.Join(base, "vol")
+	farm := filepath.Join(base, "farm")
+	for _, dir := range []string{vol, farm} {
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(vol, "big"), make([]byte, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(vol, filepath.Join(farm, "data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(farm, filepath.Join(vol, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	results, _ := New(Config{Root: farm, TopN: 10, Workers: 2}).Scan()
+	if len(results) != 0 {
+		t.Errorf("without -follow got %d results, want 0", len(results))
+	}
+
+	results, stats := New(Config{Root: farm, TopN: 10, Workers: 2, Follow: true}).Scan()
+	if len(results) != 1 {
+		t.Fatalf("with -follow got %d results, want 1", len(results))
+	}
+	if got := results[0]; got.Path != filepath.Join(farm, "data", "big") || got.Target != filepath.Join(vol, "big") {
+		t.Errorf("item = %+v, want link path and resolved target", got)
+	}
+	if stats.SymlinkLoops != 1 {
+		t.Errorf("SymlinkLoops = %d, want 1", stats.SymlinkLoops)
+	}
+
+	results, _ = New(Config{Root: farm, TopN: 10, Workers: 2, Follow: true, FollowUnder: []string{farm}}).Scan()
+	if len(results) != 0 {
+		t.Errorf("with -follow-under outside the target got %d results, want 0", len(results))
+	}
+}
+
+func TestAgeMatch(t *testing.T) {
+	now := time.Now()
+	c := Config{OlderThan: now.Add(-time.Hour), NewerThan: now.Add(-48 * time.Hour)}
+	tests := []struct {
+		t    time.Time
+		want bool
+	}{
+		{now, false},
+		{now.Add(-2 * time.Hour), true},
+		{now.Add(-72 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := c.ageMatch(tt.t); got != tt.want {
+			t.Errorf("ageMatch(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+	if !(Config{}).ageMatch(now) {
+		t.Error("ageMatch with no cutoffs = false, want true")
+	}
+}
@@ -0,0 +1,33 @@
+package scanner
+
+import (
+	"os"
+	"time"
+)
+
+// StatFile describes the file at path the way a scan would, without
+// following a final symlink. Time is the modification time.
+func StatFile(path string) (FileItem, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return FileItem{}, err
+	}
+	st := statDetails(info)
+	return FileItem{
+		Size:      info.Size(),
+		Path:      path,
+		Apparent:  info.Size(),
+		Allocated: st.allocated,
+		ID:        st.id,
+		Nlink:     st.nlink,
+		Uid:       st.uid,
+		Time:      info.ModTime(),
+	}, nil
+}
+
+// Times returns the access and change times of info. Where the platform
+// does not report them they are the modification time.
+func Times(info os.FileInfo) (atime, ctime time.Time) {
+	st := statDetails(info)
+	return st.atime, st.ctime
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package scanner
+
+import "os"
+
+func statDetails(info os.FileInfo) sysStat {
+	return sysStat{allocated: info.Size(), nlink: 1, atime: info.ModTime(), ctime: info.ModTime()}
+}
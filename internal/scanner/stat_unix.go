@@ -0,0 +1,26 @@
+//go:build linux || darwin
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+// statDetails extracts the platform fields of info. st_blocks is reported in
+// 512-byte units regardless of the filesystem block size.
+func statDetails(info os.FileInfo) sysStat {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return sysStat{allocated: info.Size(), nlink: 1, atime: info.ModTime(), ctime: info.ModTime()}
+	}
+	atime, ctime := statTimes(st)
+	return sysStat{
+		id:        FileID{Dev: uint64(st.Dev), Ino: uint64(st.Ino)},
+		nlink:     uint64(st.Nlink),
+		uid:       st.Uid,
+		allocated: int64(st.Blocks) * 512,
+		atime:     atime,
+		ctime:     ctime,
+	}
+}
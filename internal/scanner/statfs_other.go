@@ -0,0 +1,5 @@
+//go:build !linux && !darwin
+
+package scanner
+
+func fsUsedBytes(path string) int64 { return 0 }
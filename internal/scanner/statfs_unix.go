@@ -0,0 +1,14 @@
+//go:build linux || darwin
+
+package scanner
+
+import "syscall"
+
+// fsUsedBytes returns the used space of the filesystem holding path.
+func fsUsedBytes(path string) int64 {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0
+	}
+	return int64(st.Blocks-st.Bfree) * int64(st.Bsize)
+}
@@ -0,0 +1,60 @@
+package scanner
+
+import "time"
+
+// TimeField selects which timestamp age filters and the time column use.
+type TimeField int
+
+const (
+	ModTime TimeField = iota
+	AccessTime
+	ChangeTime
+)
+
+// ParseTimeField parses the -time flag value.
+func ParseTimeField(s string) (TimeField, bool) {
+	switch s {
+	case "mtime", "modified":
+		return ModTime, true
+	case "atime", "accessed":
+		return AccessTime, true
+	case "ctime", "changed":
+		return ChangeTime, true
+	}
+	return ModTime, false
+}
+
+func (f TimeField) String() string {
+	switch f {
+	case AccessTime:
+		return "atime"
+	case ChangeTime:
+		return "ctime"
+	default:
+		return "mtime"
+	}
+}
+
+// Title is the column heading for the field.
+func (f TimeField) Title() string {
+	switch f {
+	case AccessTime:
+		return "Accessed"
+	case ChangeTime:
+		return "Changed"
+	default:
+		return "Modified"
+	}
+}
+
+// ageMatch reports whether t satisfies the OlderThan and NewerThan cutoffs.
+// Zero cutoffs are ignored.
+func (c Config) ageMatch(t time.Time) bool {
+	if !c.OlderThan.IsZero() && !t.Before(c.OlderThan) {
+		return false
+	}
+	if !c.NewerThan.IsZero() && !t.After(c.NewerThan) {
+		return false
+	}
+	return true
+}
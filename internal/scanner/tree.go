@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// treeFiles bounds the files Dir.Top keeps per directory.
+const treeFiles = 10
+
+// Dir is a directory of the scanned tree, with the cumulative size of the
+// regular files below it. Only directories holding files, directly or
+// further down, are present.
+type Dir struct {
+	Path string
+	// Size and Files total every file below Path, like a ByDir item with
+	// no depth limit. Time is the most recent time of those files.
+	Size  int64
+	Files int64
+	Time  time.Time
+	// Dirs are the subdirectories, largest first.
+	Dirs []*Dir
+	// Top lists the largest files directly in Path that meet
+	// Config.MinBytes, at most treeFiles of them, largest first. Smaller
+	// ones are only counted in Size.
+	Top []FileItem
+
+	parent *Dir
+}
+
+// Own returns the size and count of the files directly in d, which is
+// what is left after the subdirectories.
+func (d *Dir) Own() (size, files int64) {
+	size, files = d.Size, d.Files
+	for _, c := range d.Dirs {
+		size -= c.Size
+		files -= c.Files
+	}
+	return size, files
+}
+
+// Find returns the directory at path below d, or nil.
+func (d *Dir) Find(path string) *Dir {
+	if path == d.Path {
+		return d
+	}
+	for _, c := range d.Dirs {
+		if within(path, c.Path) {
+			return c.Find(path)
+		}
+	}
+	return nil
+}
+
+// tree builds a Dir per root from the files a scan keeps.
+type tree struct {
+	mu   sync.Mutex
+	min  int64
+	dirs map[string]*Dir
+}
+
+func newTree(min int64) *tree {
+	return &tree{min: min, dirs: make(map[string]*Dir)}
+}
+
+func (t *tree) add(f FileItem) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent := t.dir(filepath.Dir(f.Path), f.Root)
+	for d := parent; d != nil; d = d.parent {
+		d.Size += f.Size
+		d.Files++
+		if f.Time.After(d.Time) {
+			d.Time = f.Time
+		}
+	}
+	if f.Size >= t.min && (len(parent.Top) < treeFiles || f.Size > parent.Top[len(parent.Top)-1].Size) {
+		i := sort.Search(len(parent.Top), func(i int) bool { return parent.Top[i].Size < f.Size })
+		parent.Top = append(parent.Top, FileItem{})
+		copy(parent.Top[i+1:], parent.Top[i:])
+		parent.Top[i] = f
+		if len(parent.Top) > treeFiles {
+			parent.Top = parent.Top[:treeFiles]
+		}
+	}
+}
+
+// dir returns the node for path, creating it and any missing ancestors up
+// to root.
+func (t *tree) dir(path, root string) *Dir {
+	if d, ok := t.dirs[path]; ok {
+		return d
+	}
+	d := &Dir{Path: path}
+	t.dirs[path] = d
+	if up := filepath.Dir(path); path != root && up != path {
+		d.parent = t.dir(up, root)
+		d.parent.Dirs = append(d.parent.Dirs, d)
+	}
+	return d
+}
+
+// roots returns the tree of each root, in order, with children sorted
+// largest first. Roots without files get an empty Dir.
+func (t *tree) roots(roots []string) []*Dir {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]*Dir, len(roots))
+	for i, r := range roots {
+		d, ok := t.dirs[r]
+		if !ok {
+			d = &Dir{Path: r}
+		}
+		sortDirs(d)
+		out[i] = d
+	}
+	return out
+}
+
+func sortDirs(d *Dir) {
+	sort.Slice(d.Dirs, func(i, j int) bool {
+		a, b := d.Dirs[i], d.Dirs[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Path < b.Path
+	})
+	for _, c := range d.Dirs {
+		sortDirs(c)
+	}
+}
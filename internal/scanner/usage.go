@@ -0,0 +1,48 @@
+package scanner
+
+// Usage selects which size of a file drives MinBytes, ranking and totals.
+type Usage int
+
+const (
+	// UsageApparent uses the file length as reported by stat.
+	UsageApparent Usage = iota
+	// UsageAllocated uses the blocks actually allocated on disk, which is
+	// smaller for sparse or compressed files.
+	UsageAllocated
+)
+
+// ParseUsage parses the -usage flag value.
+func ParseUsage(s string) (Usage, bool) {
+	switch s {
+	case "apparent":
+		return UsageApparent, true
+	case "allocated", "disk":
+		return UsageAllocated, true
+	}
+	return UsageApparent, false
+}
+
+func (u Usage) String() string {
+	if u == UsageAllocated {
+		return "allocated"
+	}
+	return "apparent"
+}
+
+// sparseThreshold is the allocated/apparent ratio below which a file's
+// apparent size is considered misleading.
+const sparseThreshold = 0.5
+
+// AllocRatio returns allocated bytes as a fraction of apparent bytes.
+func (f FileItem) AllocRatio() float64 {
+	if f.Apparent <= 0 {
+		return 1
+	}
+	return float64(f.Allocated) / float64(f.Apparent)
+}
+
+// Sparse reports whether the file occupies much less space on disk than its
+// apparent size suggests.
+func (f FileItem) Sparse() bool {
+	return f.AllocRatio() < sparseThreshold
+}
@@ -0,0 +1,192 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// job is a candidate file and the index of the root it was found under.
+type job struct {
+	path string
+	root int
+}
+
+// walker feeds the paths of candidate files under one root to the workers.
+// It owns the directory-level decisions: excludes, -xdev and symlink
+// following. Each root gets its own walker.
+type walker struct {
+	ctx     context.Context
+	config  Config
+	ex      excludes
+	errs    *errorLog
+	paths   chan<- job
+	root    string
+	rootIdx int
+
+	dirsWalked *atomic.Int64
+	currentDir *atomic.Value
+
+	// frames is the stack of ignore file rule sets for the directories
+	// being walked, innermost last.
+	frames []ignoreFrame
+
+	rootDev uint64
+	skipped []string
+	// visited holds every directory entered when following symlinks, so a
+	// link back into an already walked tree is not walked again.
+	visited map[FileID]bool
+	loops   int64
+}
+
+// walk walks dir, reporting paths as if dir were located at shownAs. The two
+// differ when dir is the target of a followed symlink.
+func (w *walker) walk(dir, shownAs string) {
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		select {
+		case <-w.ctx.Done():
+			return filepath.SkipAll
+		default:
+		}
+
+		shown := shownAs + path[len(dir):]
+		if err != nil {
+			w.errs.record(shown, "walk", err)
+			return nil
+		}
+		if path != dir && excludedBy(lastMatch(w.rules(filepath.Dir(shown)), shown, d.IsDir())) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&os.ModeSymlink != 0 {
+			if w.config.Follow {
+				return w.follow(path, shown)
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if !w.enter(d, path, shown) {
+				return filepath.SkipDir
+			}
+			w.dirsWalked.Add(1)
+			w.currentDir.Store(shown)
+			if w.config.IgnoreFiles {
+				w.pushIgnores(path, shown)
+			}
+			return nil
+		}
+		return w.send(shown)
+	})
+}
+
+// rules returns the rules in effect for entries of the directory shown as
+// dir, dropping the frames of directories the walk has left.
+func (w *walker) rules(dir string) []Rule {
+	for len(w.frames) > 0 && !within(dir, w.frames[len(w.frames)-1].dir) {
+		w.frames = w.frames[:len(w.frames)-1]
+	}
+	if len(w.frames) == 0 {
+		return w.ex.rules
+	}
+	return w.frames[len(w.frames)-1].rules
+}
+
+// pushIgnores reads the ignore files of the directory at path, shown as
+// shown, and makes their rules apply below it.
+func (w *walker) pushIgnores(path, shown string) {
+	more, err := readIgnoreFiles(path, shown)
+	if err != nil {
+		w.errs.record(shown, "read", err)
+	}
+	if len(more) == 0 {
+		return
+	}
+	parent := w.rules(shown)
+	rules := append(parent[:len(parent):len(parent)], more...)
+	w.frames = append(w.frames, ignoreFrame{dir: shown, rules: rules})
+}
+
+// enter reports whether the walk should descend into the directory at path.
+func (w *walker) enter(d os.DirEntry, path, shown string) bool {
+	checkDev := w.config.OneFileSystem && path != w.root
+	if !checkDev && !w.config.Follow {
+		return true
+	}
+	info, err := d.Info()
+	if err != nil {
+		w.errs.record(shown, "lstat", err)
+		return false
+	}
+	id := statDetails(info).id
+	if checkDev && id.Dev != w.rootDev {
+		w.skipped = append(w.skipped, shown)
+		return false
+	}
+	if w.config.Follow {
+		if w.visited[id] {
+			return false
+		}
+		w.visited[id] = true
+	}
+	return true
+}
+
+// follow resolves the symlink at path and queues its target, walking it
+// under the link's name when it is a directory.
+func (w *walker) follow(path, shown string) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		w.errs.record(shown, "readlink", err)
+		return nil
+	}
+	if !w.followable(target) {
+		return nil
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		w.errs.record(shown, "stat", err)
+		return nil
+	}
+	switch {
+	case info.IsDir():
+		if w.visited[statDetails(info).id] {
+			w.loops++
+			return nil
+		}
+		w.walk(target, shown)
+		if w.ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+	case info.Mode().IsRegular():
+		return w.send(shown)
+	}
+	return nil
+}
+
+// followable reports whether a symlink target lies under one of the
+// configured FollowUnder prefixes. An empty list allows any target.
+func (w *walker) followable(target string) bool {
+	if len(w.config.FollowUnder) == 0 {
+		return true
+	}
+	for _, prefix := range w.config.FollowUnder {
+		prefix = filepath.Clean(prefix)
+		if target == prefix || strings.HasPrefix(target, prefix+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *walker) send(path string) error {
+	select {
+	case w.paths <- job{path: path, root: w.rootIdx}:
+		return nil
+	case <-w.ctx.Done():
+		return filepath.SkipAll
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !linux && !darwin
+
+package trash
+
+func device(path string) (uint64, bool) { return 0, false }
@@ -0,0 +1,260 @@
+// Package trash moves files to the freedesktop.org Trash, following the XDG
+// Trash specification 1.0.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const dateLayout = "2006-01-02T15:04:05"
+
+// Entry describes a file that was moved to a trash directory.
+type Entry struct {
+	// Original is the absolute path the file was trashed from.
+	Original string `json:"original"`
+	// Dir is the trash directory holding the files and info subdirectories.
+	Dir string `json:"dir"`
+	// Name is the file's name inside Dir/files, and of its .trashinfo.
+	Name    string    `json:"name"`
+	Deleted time.Time `json:"deleted"`
+}
+
+// ErrConflict is returned by Restore when a file already exists at the
+// original location.
+var ErrConflict = errors.New("a file already exists at the original path")
+
+// FilePath is where the trashed file now lives.
+func (e Entry) FilePath() string { return filepath.Join(e.Dir, "files", e.Name) }
+
+// InfoPath is the location of the entry's .trashinfo metadata.
+func (e Entry) InfoPath() string { return filepath.Join(e.Dir, "info", e.Name+".trashinfo") }
+
+// HomeDir returns the user's home trash, $XDG_DATA_HOME/Trash.
+func HomeDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// Put moves path into the trash. Files on the same filesystem as the home
+// trash go there; files on other mounts go to that mount's .Trash/$UID or
+// .Trash-$UID directory. If neither is usable the file is copied into the
+// home trash and the original removed.
+func Put(path string) (Entry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	if info.IsDir() {
+		return Entry{}, fmt.Errorf("trash %s: directories are not supported", path)
+	}
+
+	home, err := HomeDir()
+	if err != nil {
+		return Entry{}, err
+	}
+	if sameDevice(path, existingAncestor(home)) {
+		return put(path, home, path)
+	}
+	if top := mountTop(path); top != "" {
+		if dir, ok := topdirTrash(top); ok {
+			rel, err := filepath.Rel(top, path)
+			if err == nil {
+				if e, err := put(path, dir, rel); err == nil {
+					return e, nil
+				}
+			}
+		}
+	}
+	return put(path, home, path)
+}
+
+// Restore moves a trashed file back to its original path and removes its
+// metadata. It never overwrites: if something now exists at the original
+// path it returns an error wrapping ErrConflict and leaves the entry alone.
+func Restore(e Entry) error {
+	if _, err := os.Lstat(e.Original); err == nil {
+		return fmt.Errorf("restore %s: %w", e.Original, ErrConflict)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Original), 0o755); err != nil {
+		return err
+	}
+	if err := Move(e.FilePath(), e.Original); err != nil {
+		return err
+	}
+	return os.Remove(e.InfoPath())
+}
+
+// put reserves a name in dir by creating its .trashinfo exclusively, then
+// moves the file. infoPath is the Path= value: absolute for the home trash,
+// relative to the mount for a topdir trash.
+func put(path, dir, infoPath string) (Entry, error) {
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	e := Entry{Original: path, Dir: dir, Deleted: time.Now()}
+	if err := reserve(&e, infoPath); err != nil {
+		return Entry{}, err
+	}
+
+	if err := Move(path, e.FilePath()); err != nil {
+		os.Remove(e.InfoPath())
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// reserve picks a free name for e and writes its .trashinfo. Creating the
+// info file exclusively is what claims the name.
+func reserve(e *Entry, infoPath string) error {
+	base := filepath.Base(e.Original)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	for n := 1; ; n++ {
+		e.Name = base
+		if n > 1 {
+			e.Name = stem + "." + strconv.Itoa(n) + ext
+		}
+		f, err := os.OpenFile(e.InfoPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(e.FilePath()); err == nil {
+			// A stray file without metadata; leave it alone.
+			f.Close()
+			os.Remove(e.InfoPath())
+			continue
+		}
+		_, err = fmt.Fprintf(f, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			escapePath(infoPath), e.Deleted.Format(dateLayout))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(e.InfoPath())
+		}
+		return err
+	}
+}
+
+// Move renames src to dst, falling back to copy and remove when they are on
+// different filesystems.
+func Move(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// topdirTrash returns a usable trash directory on the mount rooted at top:
+// $top/.Trash/$uid if $top/.Trash is a sticky, non-symlink directory, else
+// $top/.Trash-$uid, created if needed.
+func topdirTrash(top string) (string, bool) {
+	uid := strconv.Itoa(os.Getuid())
+	shared := filepath.Join(top, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, uid)
+		if err := os.MkdirAll(dir, 0o700); err == nil {
+			return dir, true
+		}
+	}
+	dir := filepath.Join(top, ".Trash-"+uid)
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", false
+	}
+	if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return dir, true
+}
+
+// mountTop returns the top directory of the filesystem holding path.
+func mountTop(path string) string {
+	dir := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir || !sameDevice(dir, parent) {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// existingAncestor returns path or its closest existing parent.
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+func sameDevice(a, b string) bool {
+	da, ok1 := device(a)
+	db, ok2 := device(b)
+	return ok1 && ok2 && da == db
+}
+
+// escapePath percent-encodes p as required for the Path= key, keeping
+// slashes.
+func escapePath(p string) string {
+	return (&url.URL{Path: p}).EscapedPath()
+}
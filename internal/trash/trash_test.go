@@ -0,0 +1,85 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPut(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+
+	for i := 0; i < 2; i++ {
+		path := filepath.Join(dir, "my file.log")
+		if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		e, err := Put(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("original still exists: %v", err)
+		}
+		if got, err := os.ReadFile(e.FilePath()); err != nil || string(got) != "hello" {
+			t.Errorf("trashed file = %q, %v", got, err)
+		}
+
+		wantName := "my file.log"
+		if i == 1 {
+			wantName = "my file.2.log"
+		}
+		if e.Name != wantName || e.Dir != filepath.Join(dir, "data", "Trash") {
+			t.Errorf("entry = %+v, want name %q in the home trash", e, wantName)
+		}
+
+		info, err := os.ReadFile(e.InfoPath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(info), "[Trash Info]\nPath="+escapePath(path)+"\nDeletionDate=") {
+			t.Errorf("trashinfo = %q", info)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	if got := escapePath("/home/u/a b%.txt"); got != "/home/u/a%20b%25.txt" {
+		t.Errorf("escapePath = %q", got)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	path := filepath.Join(dir, "f")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e, err := Put(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("new"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(e); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Restore over an existing file = %v, want ErrConflict", err)
+	}
+
+	os.Remove(path)
+	if err := Restore(e); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old" {
+		t.Errorf("restored content = %q, want %q", got, "old")
+	}
+	if _, err := os.Lstat(e.InfoPath()); !os.IsNotExist(err) {
+		t.Errorf("trashinfo still exists: %v", err)
+	}
+}
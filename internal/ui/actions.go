@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/natemollica-nm/topn/internal/cleanup"
+)
+
+// action is one entry of the action menu.
+type action struct {
+	strategy cleanup.Strategy
+	label    string
+	// done is the past tense for the status line, e.g. "Compressed".
+	done string
+}
+
+var actions = []action{
+	{cleanup.Trash, "Move to trash", "Moved to the trash"},
+	{cleanup.Unlink, "Delete permanently", "Deleted"},
+	{cleanup.Truncate, "Truncate to zero bytes", "Truncated"},
+	{cleanup.Gzip, "Compress with gzip", "Compressed"},
+	{cleanup.Zstd, "Compress with zstd", "Compressed"},
+	{cleanup.Archive, "Move to archive directory", "Archived"},
+}
+
+func actionFor(s cleanup.Strategy) action {
+	for _, a := range actions {
+		if a.strategy == s {
+			return a
+		}
+	}
+	return actions[0]
+}
+
+// unavailable explains why a cannot be used right now, or returns "".
+func (m Model) unavailable(a action) string {
+	if m.opts.DryRun && a.strategy != cleanup.Trash && a.strategy != cleanup.Unlink {
+		return "plans only cover trash and delete"
+	}
+	r := cleanup.Remover{Strategy: a.strategy, ArchiveDir: m.opts.ArchiveDir}
+	if err := r.Check(); err != nil {
+		if a.strategy == cleanup.Archive && m.opts.ArchiveDir == "" {
+			return "set -archive-dir"
+		}
+		return err.Error()
+	}
+	return ""
+}
+
+// actionsView is the menu of things that can be done to the selection.
+func (m Model) actionsView() string {
+	var b strings.Builder
+	b.WriteString(TitleStyle.Render("🛠  Choose Action"))
+	b.WriteString("\n\n")
+	b.WriteString(InfoStyle.Render(fmt.Sprintf("%d selected files", len(m.selected))))
+	b.WriteString("\n\n")
+
+	for i, a := range actions {
+		cursor := "  "
+		if i == m.actionCursor {
+			cursor = "▶ "
+		}
+		line := cursor + a.label
+		switch why := m.unavailable(a); {
+		case why != "":
+			line = HelpStyle.Render(fmt.Sprintf("%s (%s)", line, why))
+		case i == m.actionCursor:
+			line = SelectedStyle.Render(line)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(HelpStyle.Render("↑/↓ to choose • enter to continue • esc to cancel"))
+	return b.String()
+}
+
+// confirmPrompt is the question asked before running m.action on n files.
+func (m Model) confirmPrompt(n int) string {
+	if m.opts.DryRun {
+		return InfoStyle.Render(fmt.Sprintf("Dry run: write a plan to %s %s %d selected files?",
+			m.opts.PlanPath, m.action, n))
+	}
+	var style lipgloss.Style
+	var text string
+	switch m.action {
+	case cleanup.Unlink:
+		style, text = ErrorStyle, fmt.Sprintf("Permanently delete %d selected files?", n)
+	case cleanup.Truncate:
+		style, text = ErrorStyle, fmt.Sprintf("Truncate %d selected files to zero bytes? Their contents are lost, but the files stay in place.", n)
+	case cleanup.Gzip, cleanup.Zstd:
+		style, text = WarningStyle, fmt.Sprintf("Compress %d selected files with %s, replacing each with a %s file?", n, m.action, m.action.Extension())
+	case cleanup.Archive:
+		style, text = WarningStyle, fmt.Sprintf("Move %d selected files to %s?", n, m.opts.ArchiveDir)
+	default:
+		style, text = WarningStyle, fmt.Sprintf("Move %d selected files to the trash?", n)
+	}
+	return style.Render(text)
+}
@@ -0,0 +1,44 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+)
+
+// errorsView lists the walk and stat errors recorded by the last scan.
+func (m Model) errorsView() string {
+	var b strings.Builder
+	b.WriteString(TitleStyle.Render("⚠️  Scan Errors"))
+	b.WriteString("\n\n")
+	b.WriteString(m.errorsPort.View())
+	b.WriteString("\n\n")
+	b.WriteString(HelpStyle.Render("↑/↓ to scroll • e or esc to return"))
+	return b.String()
+}
+
+// errorsContent renders the error summary and records for the viewport.
+func (m Model) errorsContent() string {
+	c := m.stats.ErrorCounts
+	if c.Total() == 0 {
+		return SuccessStyle.Render("No errors during the last scan")
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s permission denied • %s not found • %s I/O • %s other\n\n",
+		ErrorStyle.Render(fmt.Sprintf("%d", c.Permission)),
+		WarningStyle.Render(fmt.Sprintf("%d", c.NotExist)),
+		ErrorStyle.Render(fmt.Sprintf("%d", c.IO)),
+		InfoStyle.Render(fmt.Sprintf("%d", c.Other)),
+	))
+	for _, e := range m.stats.Errors {
+		b.WriteString(fmt.Sprintf("%s %s %s\n",
+			InfoStyle.Render(fmt.Sprintf("%-10s", e.Op)),
+			ErrorStyle.Render(fmt.Sprintf("%-26s", e.Err)),
+			PathStyle.Render(e.Path),
+		))
+	}
+	if dropped := c.Total() - int64(len(m.stats.Errors)); dropped > 0 {
+		b.WriteString(PathStyle.Render(fmt.Sprintf("... and %d more not recorded", dropped)))
+	}
+	return b.String()
+}
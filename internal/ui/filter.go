@@ -0,0 +1,91 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+// fuzzyMatch reports whether the runes of query appear in path in order,
+// and returns the rune positions they matched. Matching runs from the end
+// so hits land in the file name rather than the first directory that
+// happens to contain the letters. It is case-insensitive unless query has
+// an upper-case letter.
+func fuzzyMatch(path, query string) ([]int, bool) {
+	q := []rune(query)
+	if len(q) == 0 {
+		return nil, true
+	}
+	fold := strings.ToLower(query) == query
+	p := []rune(path)
+	pos := make([]int, len(q))
+	j := len(q) - 1
+	for i := len(p) - 1; i >= 0 && j >= 0; i-- {
+		r := p[i]
+		if fold {
+			r = unicode.ToLower(r)
+		}
+		if r == q[j] {
+			pos[j] = i
+			j--
+		}
+	}
+	if j >= 0 {
+		return nil, false
+	}
+	return pos, true
+}
+
+// filterItems returns the items whose path fuzzy matches query, keeping
+// their order, along with the matched positions of each.
+func filterItems(items []scanner.FileItem, query string) ([]scanner.FileItem, [][]int) {
+	if query == "" {
+		return items, make([][]int, len(items))
+	}
+	var shown []scanner.FileItem
+	var matches [][]int
+	for _, it := range items {
+		if pos, ok := fuzzyMatch(it.Path, query); ok {
+			shown = append(shown, it)
+			matches = append(matches, pos)
+		}
+	}
+	return shown, matches
+}
+
+// highlight renders path with the runes at pos in MatchStyle. Consecutive
+// matches share one styled run to keep the escape codes short, since the
+// table truncates cells by their raw length.
+func highlight(path string, pos []int) string {
+	if len(pos) == 0 {
+		return PathStyle.Render(path)
+	}
+	var b strings.Builder
+	p := []rune(path)
+	start, matched := 0, false
+	flush := func(end int) {
+		if end == start {
+			return
+		}
+		if matched {
+			b.WriteString(MatchStyle.Render(string(p[start:end])))
+		} else {
+			b.WriteString(PathStyle.Render(string(p[start:end])))
+		}
+		start = end
+	}
+	next := 0
+	for i := range p {
+		hit := next < len(pos) && pos[next] == i
+		if hit {
+			next++
+		}
+		if hit != matched {
+			flush(i)
+			matched = hit
+		}
+	}
+	flush(len(p))
+	return b.String()
+}
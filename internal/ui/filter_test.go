@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		path, query string
+		want        []int
+		ok          bool
+	}{
+		{"/srv/app/build.log", "", nil, true},
+		// Hits are taken from the end, so they land in the file name.
+		{"/srv/app/build.log", "blog", []int{9, 15, 16, 17}, true},
+		{"/srv/App/x", "app", []int{5, 6, 7}, true},
+		// An upper-case query matches case exactly.
+		{"/srv/app/x", "App", nil, false},
+		{"/srv/app/x", "xa", nil, false},
+		{"/tmp/café.iso", "éiso", []int{8, 10, 11, 12}, true},
+	}
+	for _, tt := range tests {
+		got, ok := fuzzyMatch(tt.path, tt.query)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, %v; want %v, %v", tt.path, tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFilterItems(t *testing.T) {
+	items := []scanner.FileItem{{Path: "/a/core"}, {Path: "/b/log"}, {Path: "/c/core.1"}}
+	shown, matches := filterItems(items, "core")
+	if len(shown) != 2 || shown[0].Path != "/a/core" || shown[1].Path != "/c/core.1" {
+		t.Fatalf("shown = %v", shown)
+	}
+	if len(matches) != 2 || len(matches[1]) != 4 {
+		t.Errorf("matches = %v", matches)
+	}
+	if shown, _ := filterItems(items, ""); len(shown) != 3 {
+		t.Errorf("empty filter showed %d items", len(shown))
+	}
+}
@@ -1,42 +1,118 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/natemollica-nm/topn/internal/audit"
+	"github.com/natemollica-nm/topn/internal/cleanup"
+	"github.com/natemollica-nm/topn/internal/openfiles"
 	"github.com/natemollica-nm/topn/internal/scanner"
 	"github.com/natemollica-nm/topn/internal/utils"
 )
 
 type state int
 
+const dateLayout = "2006-01-02"
+
+// defaultPathWidth is the width of the path column without the preview pane.
+const defaultPathWidth = 60
+
 const (
 	stateScanning state = iota
 	stateViewing
+	stateFiltering
+	stateActions
 	stateConfirming
+	stateChecking
 	stateHelp
+	stateErrors
+	stateTree
 )
 
 type Model struct {
-	state     state
-	table     table.Model
-	progress  progress.Model
-	help      help.Model
-	keys      keyMap
-	results   []scanner.FileItem
-	stats     scanner.Stats
-	config    scanner.Config
-	selected  map[int]bool
-	message   string
-	err       error
-	width     int
-	height    int
+	state        state
+	table        table.Model
+	progress     progress.Model
+	spinner      spinner.Model
+	errorsPort   viewport.Model
+	help         help.Model
+	keys         keyMap
+	results      []scanner.FileItem
+	stats        scanner.Stats
+	config       scanner.Config
+	opts         Options
+	selected     selection
+	message      string
+	err          error
+	width        int
+	height       int
+	scanID       int
+	scanProgress scanner.Progress
+	cancel       context.CancelFunc
+	// holders lists the processes holding selected files open, looked up
+	// when the confirmation screen is shown.
+	holders map[string][]openfiles.Process
+	// checkID identifies the holders lookup the confirmation waits for.
+	checkID int
+	// action is what the confirmation screen will do to the selection, and
+	// actionCursor the highlighted entry of the action menu.
+	action       cleanup.Strategy
+	actionCursor int
+	// sort is the order of results, and owners caches the user names
+	// shown in and sorted by the owner column.
+	sort   sortOrder
+	owners ownerNames
+	// filter narrows the table to paths that fuzzy match its value. visible
+	// holds the results it lets through, in table order, and matches the
+	// matched rune positions of each.
+	filter  textinput.Model
+	visible []scanner.FileItem
+	matches [][]int
+	// tree is the directory tree of each root from the last scan, and
+	// treePath the directories entered in the tree view, innermost last.
+	tree       []*scanner.Dir
+	treePath   []*scanner.Dir
+	treeCursor int
+	// treeOnScan opens the tree view when the scan building it completes.
+	treeOnScan bool
+	// showPreview toggles the preview pane beside the table, which then
+	// narrows the path column to pathWidth.
+	showPreview bool
+	pane        previewPane
+	pathWidth   int
+}
+
+// Options controls how the TUI acts on the files it shows.
+type Options struct {
+	// Strategy is how selected files are removed. The zero value moves
+	// them to the trash.
+	Strategy cleanup.Strategy
+	// DryRun writes a removal plan to PlanPath instead of removing files.
+	DryRun   bool
+	PlanPath string
+	// Audit records every removal. Nil disables auditing.
+	Audit *audit.Log
+	// History keeps trashed batches so they can be undone. Nil disables undo.
+	History *cleanup.History
+	// Force removes files that processes hold open instead of skipping them.
+	Force bool
+	// ArchiveDir is where the archive action moves files.
+	ArchiveDir string
+	// Protect marks paths that are shown but can never be selected.
+	Protect cleanup.Protect
 }
 
 type keyMap struct {
@@ -44,22 +120,33 @@ type keyMap struct {
 	Down      key.Binding
 	Select    key.Binding
 	Remove    key.Binding
+	Actions   key.Binding
 	Rescan    key.Binding
 	Help      key.Binding
 	Quit      key.Binding
 	Confirm   key.Binding
 	Cancel    key.Binding
 	SelectAll key.Binding
+	Stop      key.Binding
+	Errors    key.Binding
+	Undo      key.Binding
+	Truncate  key.Binding
+	Sort      key.Binding
+	Filter    key.Binding
+	Tree      key.Binding
+	Open      key.Binding
+	Back      key.Binding
+	Preview   key.Binding
 }
 
 func (k keyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Select, k.Remove, k.Rescan, k.Help, k.Quit}
+	return []key.Binding{k.Select, k.Actions, k.Remove, k.Rescan, k.Help, k.Quit}
 }
 
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Up, k.Down, k.Select, k.SelectAll},
-		{k.Remove, k.Rescan, k.Help, k.Quit},
+		{k.Up, k.Down, k.Select, k.SelectAll, k.Sort, k.Filter, k.Preview},
+		{k.Actions, k.Remove, k.Undo, k.Rescan, k.Tree, k.Errors, k.Help, k.Quit},
 	}
 }
 
@@ -67,16 +154,28 @@ var keys = keyMap{
 	Up:        key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "move up")),
 	Down:      key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "move down")),
 	Select:    key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "select/deselect")),
-	Remove:    key.NewBinding(key.WithKeys("enter", "d"), key.WithHelp("enter/d", "delete selected")),
+	Remove:    key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete selected")),
+	Actions:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "choose action")),
 	Rescan:    key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rescan")),
 	Help:      key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "toggle help")),
 	Quit:      key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "quit")),
 	Confirm:   key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
 	Cancel:    key.NewBinding(key.WithKeys("n", "esc"), key.WithHelp("n/esc", "cancel")),
 	SelectAll: key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "select all")),
+	Stop:      key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "stop scan")),
+	Errors:    key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "show scan errors")),
+	Undo:      key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo last removal")),
+	Truncate:  key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "truncate open files instead")),
+	Sort:      key.NewBinding(key.WithKeys("1", "2", "3", "4", "5"), key.WithHelp("1-5", "sort by column, again to reverse")),
+	Filter:    key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter paths")),
+	Tree:      key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "directory tree")),
+	Open:      key.NewBinding(key.WithKeys("enter", "right", "l"), key.WithHelp("enter/→", "open directory")),
+	Back:      key.NewBinding(key.WithKeys("backspace", "left", "h"), key.WithHelp("backspace/←", "parent directory")),
+	Preview:   key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "toggle preview")),
 }
 
 type scanCompleteMsg struct {
+	id      int
 	results []scanner.FileItem
 	stats   scanner.Stats
 }
@@ -87,15 +186,19 @@ type removeCompleteMsg struct {
 	message string
 }
 
-func NewModel(config scanner.Config) Model {
-	columns := []table.Column{
-		{Title: "Select", Width: 8},
-		{Title: "Size", Width: 10},
-		{Title: "Path", Width: 60},
+// planWrittenMsg reports a dry run. Nothing changed on disk, so unlike
+// removeCompleteMsg it does not rescan.
+type planWrittenMsg struct {
+	message string
+}
+
+func NewModel(config scanner.Config, opts Options) Model {
+	if opts.Strategy == "" {
+		opts.Strategy = cleanup.Trash
 	}
 
 	t := table.New(
-		table.WithColumns(columns),
+		table.WithColumns(columns(config, sortOrder{}, defaultPathWidth)),
 		table.WithFocused(true),
 		table.WithHeight(15),
 	)
@@ -107,36 +210,139 @@ func NewModel(config scanner.Config) Model {
 	s.Selected = SelectedStyle.Copy()
 	t.SetStyles(s)
 
+	fi := textinput.New()
+	fi.Prompt = "/"
+	fi.Placeholder = "fuzzy filter on path"
+
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+	sp.Style = ProgressStyle
+
 	return Model{
-		state:    stateScanning,
-		table:    t,
-		progress: progress.New(progress.WithDefaultGradient()),
-		help:     help.New(),
-		keys:     keys,
-		config:   config,
-		selected: make(map[int]bool),
+		state:      stateScanning,
+		table:      t,
+		progress:   progress.New(progress.WithDefaultGradient()),
+		spinner:    sp,
+		errorsPort: viewport.New(80, 20),
+		help:       help.New(),
+		keys:       keys,
+		config:     config,
+		opts:       opts,
+		selected:   make(selection),
+		owners:     make(ownerNames),
+		filter:     fi,
+		pathWidth:  defaultPathWidth,
 	}
 }
 
+// columns returns the table columns for config, marking the one the results
+// are sorted by.
+func columns(config scanner.Config, o sortOrder, pathWidth int) []table.Column {
+	cols := []table.Column{
+		{Title: "Select", Width: 8},
+		{Title: "Size", Width: 10},
+		{Title: "Alloc", Width: 8},
+		{Title: config.TimeField.Title(), Width: 11},
+		{Title: "Owner", Width: 10},
+		{Title: "Path", Width: pathWidth},
+	}
+	if config.Mode == scanner.ByDir {
+		cols = []table.Column{
+			{Title: "Select", Width: 8},
+			{Title: "Size", Width: 10},
+			{Title: "Files", Width: 10},
+			{Title: config.TimeField.Title(), Width: 11},
+			{Title: "Directory", Width: pathWidth},
+		}
+	}
+	last := len(cols) - 1
+	switch o.field {
+	case sortSize:
+		cols[1].Title += o.arrow()
+	case sortTime:
+		cols[3].Title += o.arrow()
+	case sortOwner:
+		cols[4].Title += o.arrow()
+	case sortPath:
+		cols[last].Title += o.arrow()
+	case sortExt:
+		cols[last].Title += " (ext)" + o.arrow()
+	}
+	return cols
+}
+
 func (m Model) Init() tea.Cmd {
-	return m.startScan()
+	return func() tea.Msg { return startScanMsg{} }
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	m = next.(Model)
+	if m.state == stateViewing || m.state == stateFiltering {
+		if load := m.loadPreview(); load != nil {
+			cmd = tea.Batch(cmd, load)
+		}
+	}
+	return m, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 		m.help.Width = msg.Width
-		m.table.SetWidth(msg.Width - 4)
+		m.layout()
 		m.table.SetHeight(msg.Height - 10)
+		m.progress.Width = min(msg.Width-4, 80)
+		m.errorsPort.Width = msg.Width - 4
+		m.errorsPort.Height = msg.Height - 8
 		return m, nil
 
+	case startScanMsg:
+		cmd := m.startScan()
+		return m, cmd
+
+	case holdersMsg:
+		if m.state != stateChecking || msg.id != m.checkID {
+			return m, nil
+		}
+		m.holders = msg.holders
+		m.message = ""
+		m.state = stateConfirming
+		return m, nil
+
+	case previewMsg:
+		if msg.key == m.pane.key {
+			m.pane.data, m.pane.err = &msg.data, msg.err
+		}
+		return m, nil
+
+	case scanProgressMsg:
+		if msg.id != m.scanID {
+			return m, nil
+		}
+		m.scanProgress = msg.progress
+		return m, waitForProgress(msg.id, msg.ch)
+
+	case spinner.TickMsg:
+		if m.state != stateScanning {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
 	case tea.KeyMsg:
 		switch m.state {
 		case stateScanning:
-			if key.Matches(msg, m.keys.Quit) {
+			switch {
+			case key.Matches(msg, m.keys.Quit):
+				m.stopScan()
 				return m, tea.Quit
+			case key.Matches(msg, m.keys.Stop):
+				m.stopScan()
+				return m, nil
 			}
 
 		case stateViewing:
@@ -146,63 +352,213 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case key.Matches(msg, m.keys.Help):
 				m.state = stateHelp
 				return m, nil
+			case key.Matches(msg, m.keys.Errors):
+				m.errorsPort.SetContent(m.errorsContent())
+				m.errorsPort.GotoTop()
+				m.state = stateErrors
+				return m, nil
 			case key.Matches(msg, m.keys.Select):
-				if len(m.results) > 0 {
-					idx := m.table.Cursor()
-					m.selected[idx] = !m.selected[idx]
+				if i := m.table.Cursor(); i >= 0 && i < len(m.visible) {
+					it := m.visible[i]
+					if pat, ok := m.opts.Protect.Match(it.Path); ok {
+						m.message = fmt.Sprintf("🔒 %s is protected by %q", it.Path, pat)
+						return m, nil
+					}
+					m.selected.toggle(it)
 					m.updateTable()
 				}
-			case key.Matches(msg, m.keys.SelectAll):
-				allSelected := len(m.selected) == len(m.results)
-				m.selected = make(map[int]bool)
-				if !allSelected {
-					for i := range m.results {
-						m.selected[i] = true
-					}
+			case key.Matches(msg, m.keys.Sort):
+				f := sortKeys[msg.String()]
+				if m.config.Mode == scanner.ByDir && (f == sortExt || f == sortOwner) {
+					m.message = fmt.Sprintf("Sorting by %s is only available when ranking files", f)
+					return m, nil
 				}
+				m.sort = m.sort.next(f)
+				sortItems(m.results, m.sort, m.owners)
+				m.updateTable()
+			case key.Matches(msg, m.keys.Tree):
+				cmd := m.openTree()
+				return m, cmd
+			case key.Matches(msg, m.keys.Preview):
+				m.showPreview = !m.showPreview
+				m.pane = previewPane{}
+				m.layout()
+				return m, nil
+			case key.Matches(msg, m.keys.Filter):
+				m.state = stateFiltering
+				return m, m.filter.Focus()
+			case key.Matches(msg, m.keys.Stop) && m.filter.Value() != "":
+				m.filter.Reset()
+				m.updateTable()
+				return m, nil
+			case key.Matches(msg, m.keys.SelectAll):
+				m.selectAll()
 				m.updateTable()
-			case key.Matches(msg, m.keys.Remove):
-				if m.hasSelected() {
-					m.state = stateConfirming
+			case key.Matches(msg, m.keys.Remove), key.Matches(msg, m.keys.Actions):
+				if m.config.Mode == scanner.ByDir {
+					m.message = "Actions are only available when ranking files"
 					return m, nil
 				}
+				if !m.hasSelected() {
+					return m, nil
+				}
+				if key.Matches(msg, m.keys.Remove) {
+					return m, m.confirm(m.opts.Strategy)
+				}
+				m.state = stateActions
+				return m, nil
+			case key.Matches(msg, m.keys.Undo):
+				if m.opts.History == nil {
+					m.message = "Undo is not available"
+					return m, nil
+				}
+				m.state = stateScanning
+				return m, m.undoRemoval()
 			case key.Matches(msg, m.keys.Rescan):
 				m.state = stateScanning
 				m.results = nil
-				m.selected = make(map[int]bool)
 				m.message = ""
-				return m, m.startScan()
+				cmd := m.startScan()
+				return m, cmd
 			}
 
+		case stateFiltering:
+			switch msg.Type {
+			case tea.KeyEnter:
+				m.filter.Blur()
+				m.state = stateViewing
+				return m, nil
+			case tea.KeyEsc:
+				m.filter.Blur()
+				m.filter.Reset()
+				m.updateTable()
+				m.state = stateViewing
+				return m, nil
+			case tea.KeyUp, tea.KeyDown, tea.KeyPgUp, tea.KeyPgDown:
+				var cmd tea.Cmd
+				m.table, cmd = m.table.Update(msg)
+				return m, cmd
+			}
+			var cmd tea.Cmd
+			m.filter, cmd = m.filter.Update(msg)
+			m.updateTable()
+			return m, cmd
+
+		case stateActions:
+			switch {
+			case key.Matches(msg, m.keys.Up):
+				if m.actionCursor > 0 {
+					m.actionCursor--
+				}
+			case key.Matches(msg, m.keys.Down):
+				if m.actionCursor < len(actions)-1 {
+					m.actionCursor++
+				}
+			case key.Matches(msg, m.keys.Actions):
+				a := actions[m.actionCursor]
+				if why := m.unavailable(a); why != "" {
+					m.message = fmt.Sprintf("%s: %s", a.label, why)
+					m.state = stateViewing
+					return m, nil
+				}
+				return m, m.confirm(a.strategy)
+			case key.Matches(msg, m.keys.Cancel), key.Matches(msg, m.keys.Quit):
+				m.state = stateViewing
+			}
+			return m, nil
+
+		case stateChecking:
+			switch {
+			case key.Matches(msg, m.keys.Quit):
+				return m, tea.Quit
+			case key.Matches(msg, m.keys.Cancel):
+				m.message = ""
+				m.state = stateViewing
+			}
+			return m, nil
+
 		case stateConfirming:
 			switch {
 			case key.Matches(msg, m.keys.Confirm):
 				m.state = stateScanning
-				return m, m.removeSelected()
+				return m, m.removeSelected(false)
+			case key.Matches(msg, m.keys.Truncate):
+				if len(m.holders) > 0 && !m.opts.DryRun && m.action != cleanup.Truncate {
+					m.state = stateScanning
+					return m, m.removeSelected(true)
+				}
 			case key.Matches(msg, m.keys.Cancel):
 				m.state = stateViewing
 				return m, nil
 			}
 
+		case stateTree:
+			switch {
+			case key.Matches(msg, m.keys.Quit):
+				return m, tea.Quit
+			case key.Matches(msg, m.keys.Tree), key.Matches(msg, m.keys.Stop):
+				m.state = stateViewing
+			case key.Matches(msg, m.keys.Up):
+				if m.treeCursor > 0 {
+					m.treeCursor--
+				}
+			case key.Matches(msg, m.keys.Down):
+				if rows, _ := m.treeRows(); m.treeCursor < len(rows)-1 {
+					m.treeCursor++
+				}
+			case key.Matches(msg, m.keys.Open):
+				m.descend()
+			case key.Matches(msg, m.keys.Back):
+				m.ascend()
+			}
+			return m, nil
+
 		case stateHelp:
 			if key.Matches(msg, m.keys.Help) || key.Matches(msg, m.keys.Quit) {
 				m.state = stateViewing
 				return m, nil
 			}
+
+		case stateErrors:
+			if key.Matches(msg, m.keys.Errors) || key.Matches(msg, m.keys.Stop) || key.Matches(msg, m.keys.Quit) {
+				m.state = stateViewing
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.errorsPort, cmd = m.errorsPort.Update(msg)
+			return m, cmd
 		}
 
 	case scanCompleteMsg:
+		if msg.id != m.scanID {
+			return m, nil
+		}
 		m.state = stateViewing
+		m.cancel = nil
 		m.results = msg.results
 		m.stats = msg.stats
+		m.tree = msg.stats.Tree
+		m.restoreTree()
+		m.pane = previewPane{}
+		m.selected.retain(m.results)
+		sortItems(m.results, m.sort, m.owners)
 		m.updateTable()
+		if m.treeOnScan {
+			m.treeOnScan = false
+			m.openTree()
+		}
 		return m, nil
 
 	case removeCompleteMsg:
 		m.state = stateViewing
 		m.message = msg.message
-		m.selected = make(map[int]bool)
-		return m, m.startScan()
+		cmd := m.startScan()
+		return m, cmd
+
+	case planWrittenMsg:
+		m.state = stateViewing
+		m.message = msg.message
+		return m, nil
 	}
 
 	if m.state == stateViewing {
@@ -210,6 +566,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.table, cmd = m.table.Update(msg)
 		return m, cmd
 	}
+	if m.state == stateFiltering {
+		var cmd tea.Cmd
+		m.filter, cmd = m.filter.Update(msg)
+		return m, cmd
+	}
 
 	return m, nil
 }
@@ -218,12 +579,18 @@ func (m Model) View() string {
 	switch m.state {
 	case stateScanning:
 		return m.scanningView()
-	case stateViewing:
+	case stateViewing, stateFiltering, stateChecking:
 		return m.viewingView()
+	case stateActions:
+		return m.actionsView()
 	case stateConfirming:
 		return m.confirmingView()
 	case stateHelp:
 		return m.helpView()
+	case stateErrors:
+		return m.errorsView()
+	case stateTree:
+		return m.treeView()
 	}
 	return ""
 }
@@ -232,11 +599,61 @@ func (m Model) scanningView() string {
 	var b strings.Builder
 	b.WriteString(TitleStyle.Render("🔍 TopN - Scanning Files"))
 	b.WriteString("\n\n")
-	b.WriteString(InfoStyle.Render(fmt.Sprintf("Scanning: %s", m.config.Root)))
-	b.WriteString("\n\n")
-	b.WriteString(ProgressStyle.Render("⠋ Finding large files..."))
+	b.WriteString(InfoStyle.Render(fmt.Sprintf("Scanning: %s", strings.Join(m.config.ScanRoots(), ", "))))
 	b.WriteString("\n\n")
-	b.WriteString(HelpStyle.Render("Press q to quit"))
+
+	p := m.scanProgress
+	if p.BytesTotal > 0 {
+		b.WriteString(m.progress.ViewAs(p.Fraction()))
+		b.WriteString(PathStyle.Render(fmt.Sprintf(" of ~%s", utils.HumanSize(p.BytesTotal))))
+		b.WriteString("\n\n")
+	}
+
+	secs := p.Elapsed.Seconds()
+	if secs <= 0 {
+		secs = 1
+	}
+	b.WriteString(fmt.Sprintf("%s %s files • %s • %s dirs",
+		m.spinner.View(),
+		InfoStyle.Render(fmt.Sprintf("%d", p.FilesSeen)),
+		SizeStyle.Render(utils.HumanSize(p.BytesSeen)),
+		InfoStyle.Render(fmt.Sprintf("%d", p.DirsWalked)),
+	))
+	if p.Errors > 0 {
+		b.WriteString(ErrorStyle.Render(fmt.Sprintf(" • %d errors", p.Errors)))
+	}
+	b.WriteString("\n")
+	b.WriteString(PathStyle.Render(fmt.Sprintf("%.0f files/s • %s/s • %s",
+		float64(p.FilesSeen)/secs,
+		utils.HumanSize(int64(float64(p.BytesSeen)/secs)),
+		p.Elapsed.Round(time.Second),
+	)))
+	b.WriteString("\n")
+	if p.CurrentDir != "" {
+		b.WriteString(PathStyle.Render(truncatePath(p.CurrentDir, m.width-4)))
+		b.WriteString("\n")
+	}
+
+	if len(p.Top) > 0 {
+		b.WriteString("\n")
+		b.WriteString(HeaderStyle.Render("Largest so far"))
+		b.WriteString("\n")
+		for i, item := range p.Top {
+			if i == 5 {
+				break
+			}
+			path := item.Path
+			if item.IsDir {
+				path += "/"
+			}
+			b.WriteString(fmt.Sprintf("%s %s\n",
+				SizeStyle.Render(fmt.Sprintf("%-8s", utils.HumanSize(item.Size))),
+				PathStyle.Render(truncatePath(path, m.width-14)),
+			))
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(HelpStyle.Render("Press esc to stop and show partial results, q to quit"))
 	return b.String()
 }
 
@@ -245,16 +662,53 @@ func (m Model) viewingView() string {
 	b.WriteString(TitleStyle.Render("🔍 TopN - Large File Scanner"))
 	b.WriteString("\n\n")
 
+	if m.stats.Partial {
+		b.WriteString(WarningStyle.Render(fmt.Sprintf(
+			"⚠ Partial results: scan stopped after %d files", m.stats.FilesSeen)))
+		b.WriteString("\n\n")
+	}
+
 	if len(m.results) > 0 {
-		selectedCount := len(m.selected)
+		kept := "kept"
+		if m.config.Mode == scanner.ByDir {
+			kept = "directories"
+		}
+		selected := HeaderStyle.Render(fmt.Sprintf("%d", len(m.selected)))
+		filtered := m.filter.Value() != ""
+		if filtered {
+			selected = fmt.Sprintf("%s of %s",
+				HeaderStyle.Render(fmt.Sprintf("%d", m.selectedShown())),
+				HeaderStyle.Render(fmt.Sprintf("%d", len(m.selected))))
+		}
 		b.WriteString(fmt.Sprintf(
-			"Found %s files (%s kept >= %s) • %s selected\n\n",
+			"Found %s files (%s %s >= %s) • %s selected",
 			InfoStyle.Render(fmt.Sprintf("%d", m.stats.FilesSeen)),
 			SuccessStyle.Render(fmt.Sprintf("%d", m.stats.FilesKept)),
+			kept,
 			SizeStyle.Render(utils.HumanSize(m.config.MinBytes)),
-			HeaderStyle.Render(fmt.Sprintf("%d", selectedCount)),
+			selected,
 		))
-		b.WriteString(m.table.View())
+		if filtered {
+			b.WriteString(fmt.Sprintf(" • showing %s of %d",
+				InfoStyle.Render(fmt.Sprintf("%d", len(m.visible))), len(m.results)))
+		}
+		b.WriteString("\n\n")
+		if n := m.stats.ErrorCounts.Total(); n > 0 {
+			b.WriteString(ErrorStyle.Render(fmt.Sprintf("⚠ %d errors during scan (press e)", n)))
+			b.WriteString("\n\n")
+		}
+		if m.state == stateFiltering || filtered {
+			b.WriteString(m.filter.View())
+			if m.state != stateFiltering {
+				b.WriteString(HelpStyle.Render("  (/ to edit, esc to clear)"))
+			}
+			b.WriteString("\n")
+		}
+		if m.showPreview {
+			b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, m.table.View(), " ", m.previewView()))
+		} else {
+			b.WriteString(m.table.View())
+		}
 	} else {
 		b.WriteString(InfoStyle.Render("No files found matching criteria"))
 		b.WriteString("\n\n")
@@ -277,23 +731,25 @@ func (m Model) viewingView() string {
 
 func (m Model) confirmingView() string {
 	var b strings.Builder
-	b.WriteString(TitleStyle.Render("⚠️  Confirm Deletion"))
+	b.WriteString(TitleStyle.Render("⚠️  Confirm " + actionFor(m.action).label))
 	b.WriteString("\n\n")
 
 	selectedCount := len(m.selected)
-	b.WriteString(ErrorStyle.Render(fmt.Sprintf("Delete %d selected files?", selectedCount)))
+	b.WriteString(m.confirmPrompt(selectedCount))
 	b.WriteString("\n\n")
+	if hidden := selectedCount - m.selectedShown(); hidden > 0 {
+		b.WriteString(WarningStyle.Render(fmt.Sprintf("%d of them are hidden by the filter %q", hidden, m.filter.Value())))
+		b.WriteString("\n\n")
+	}
 
-	// Show first few files to be deleted
-	count := 0
-	for i, selected := range m.selected {
-		if selected && count < 5 && i < len(m.results) {
-			b.WriteString(PathStyle.Render(fmt.Sprintf("• %s (%s)",
-				m.results[i].Path,
-				utils.HumanSize(m.results[i].Size))))
-			b.WriteString("\n")
-			count++
+	// Show the largest few files to be deleted
+	items := m.selected.items()
+	for i, it := range items {
+		if i == 5 {
+			break
 		}
+		b.WriteString(PathStyle.Render(fmt.Sprintf("• %s (%s)", it.Path, utils.HumanSize(it.Size))))
+		b.WriteString("\n")
 	}
 
 	if selectedCount > 5 {
@@ -301,8 +757,65 @@ func (m Model) confirmingView() string {
 		b.WriteString("\n")
 	}
 
+	// A path that is itself a symlink loses only the link; one that goes
+	// through a symlinked directory names the real file, which is removed.
+	var linked, symlinks, throughDir int
+	for _, it := range items {
+		if it.Nlink > 1 {
+			linked++
+		}
+		if it.Target == "" {
+			continue
+		}
+		if info, err := os.Lstat(it.Path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			symlinks++
+		} else {
+			throughDir++
+		}
+	}
+	if symlinks > 0 {
+		b.WriteString("\n")
+		b.WriteString(WarningStyle.Render(fmt.Sprintf(
+			"↪ %d selected paths are symlinks. Only the link is removed, not its target.", symlinks)))
+		b.WriteString("\n")
+	}
+	if throughDir > 0 {
+		b.WriteString("\n")
+		b.WriteString(ErrorStyle.Render(fmt.Sprintf(
+			"↪ %d selected paths go through a symlinked directory. The target file itself is removed.", throughDir)))
+		b.WriteString("\n")
+	}
+	if linked > 0 {
+		b.WriteString("\n")
+		b.WriteString(WarningStyle.Render(fmt.Sprintf(
+			"🔗 %d selected files have other hard links. Deleting one link frees no space until every link is gone.", linked)))
+		b.WriteString("\n")
+	}
+
+	if len(m.holders) > 0 {
+		b.WriteString("\n")
+		if m.opts.Force {
+			b.WriteString(ErrorStyle.Render(fmt.Sprintf(
+				"🔒 %d selected files are open. They will be removed anyway, but their space is not freed until closed:", len(m.holders))))
+		} else {
+			b.WriteString(WarningStyle.Render(fmt.Sprintf(
+				"🔒 %d selected files are open and will be skipped, since removing them frees nothing:", len(m.holders))))
+		}
+		b.WriteString("\n")
+		for _, p := range m.selectedPaths() {
+			if procs := m.holders[p]; len(procs) > 0 {
+				b.WriteString(PathStyle.Render(fmt.Sprintf("• %s: %s", p, openfiles.Describe(procs))))
+				b.WriteString("\n")
+			}
+		}
+	}
+
 	b.WriteString("\n")
-	b.WriteString(SuccessStyle.Render("y") + " to confirm, " + ErrorStyle.Render("n/esc") + " to cancel")
+	b.WriteString(SuccessStyle.Render("y") + " to confirm, ")
+	if len(m.holders) > 0 && !m.opts.DryRun && m.action != cleanup.Truncate {
+		b.WriteString(WarningStyle.Render("t") + " to truncate open files instead, ")
+	}
+	b.WriteString(ErrorStyle.Render("n/esc") + " to cancel")
 	return b.String()
 }
 
@@ -316,52 +829,223 @@ func (m Model) helpView() string {
 	return b.String()
 }
 
+// updateTable fills the table with the results the filter lets through,
+// keeping the cursor on the file it was on when that file is still shown.
 func (m *Model) updateTable() {
-	rows := make([]table.Row, len(m.results))
-	for i, item := range m.results {
+	var cur *fileKey
+	if i := m.table.Cursor(); i >= 0 && i < len(m.visible) {
+		k := keyOf(m.visible[i])
+		cur = &k
+	}
+	m.visible, m.matches = filterItems(m.results, m.filter.Value())
+
+	rows := make([]table.Row, len(m.visible))
+	for i, item := range m.visible {
 		selected := "[ ]"
-		if m.selected[i] {
+		if m.selected.has(item) {
 			selected = SelectedStyle.Render("[✓]")
+		} else if _, ok := m.opts.Protect.Match(item.Path); ok {
+			selected = " 🔒"
+		}
+		if item.IsDir {
+			rows[i] = table.Row{
+				selected,
+				SizeStyle.Render(utils.HumanSize(item.Size)),
+				InfoStyle.Render(fmt.Sprintf("%d", item.Files)),
+				InfoStyle.Render(item.Time.Format(dateLayout)),
+				highlight(item.Path, m.matches[i]) + PathStyle.Render("/"),
+			}
+			continue
+		}
+		alloc := InfoStyle.Render(fmt.Sprintf("%.0f%%", item.AllocRatio()*100))
+		if item.Sparse() {
+			alloc = WarningStyle.Render(fmt.Sprintf("%.0f%% !", item.AllocRatio()*100))
+		}
+		path := highlight(item.Path, m.matches[i])
+		if item.Target != "" {
+			path += InfoStyle.Render(" → " + item.Target)
+		}
+		if item.Nlink > 1 {
+			path += WarningStyle.Render(fmt.Sprintf(" 🔗%d", item.Nlink))
 		}
 		rows[i] = table.Row{
 			selected,
 			SizeStyle.Render(utils.HumanSize(item.Size)),
-			PathStyle.Render(item.Path),
+			alloc,
+			InfoStyle.Render(item.Time.Format(dateLayout)),
+			InfoStyle.Render(m.owners.name(item.Uid)),
+			path,
 		}
 	}
+	cursor := 0
+	for i, it := range m.visible {
+		if cur != nil && keyOf(it) == *cur {
+			cursor = i
+			break
+		}
+	}
+	// The table renders around its cursor, so move the cursor inside the
+	// new rows before and after swapping them.
+	m.table.SetColumns(columns(m.config, m.sort, m.pathWidth))
+	m.table.SetCursor(cursor)
 	m.table.SetRows(rows)
+	m.table.SetCursor(cursor)
 }
 
 func (m Model) hasSelected() bool {
 	return len(m.selected) > 0
 }
 
-func (m Model) startScan() tea.Cmd {
-	return tea.Cmd(func() tea.Msg {
-		s := scanner.New(m.config)
-		results, stats := s.Scan()
-		return scanCompleteMsg{results: results, stats: stats}
-	})
+// confirm asks for confirmation before running s on the selection.
+func (m *Model) confirm(s cleanup.Strategy) tea.Cmd {
+	m.action = s
+	m.holders = nil
+	if s == cleanup.Truncate {
+		m.state = stateConfirming
+		return nil
+	}
+	// Finding the holders reads every process's open files, which can take
+	// a while on a busy host, so the confirmation waits for it off the
+	// event loop.
+	m.checkID++
+	m.state = stateChecking
+	m.message = "Checking whether the selected files are open… (esc to cancel)"
+	id, paths := m.checkID, m.selectedPaths()
+	return func() tea.Msg {
+		held, _ := openfiles.Holders(paths)
+		return holdersMsg{id: id, holders: held}
+	}
+}
+
+// holdersMsg carries the processes holding the selected files open, for
+// the confirmation started as check id.
+type holdersMsg struct {
+	id      int
+	holders map[string][]openfiles.Process
 }
 
-func (m Model) removeSelected() tea.Cmd {
+// selectedShown counts the selected files the filter lets through.
+func (m Model) selectedShown() int {
+	n := 0
+	for _, it := range m.visible {
+		if m.selected.has(it) {
+			n++
+		}
+	}
+	return n
+}
+
+func (m Model) selectedPaths() []string {
+	return m.selected.paths()
+}
+
+// selectAll selects every unprotected result the filter shows, or
+// deselects them when they are all selected already. Selected files the
+// filter hides are left alone.
+func (m *Model) selectAll() {
+	var selectable []scanner.FileItem
+	all := true
+	for _, it := range m.visible {
+		if _, ok := m.opts.Protect.Match(it.Path); ok {
+			continue
+		}
+		selectable = append(selectable, it)
+		all = all && m.selected.has(it)
+	}
+	if all {
+		for _, it := range selectable {
+			delete(m.selected, keyOf(it))
+		}
+		return
+	}
+	for _, it := range selectable {
+		if !m.selected.has(it) {
+			m.selected.toggle(it)
+		}
+	}
+}
+
+// removeSelected removes the selected files. With truncateOpen, files that
+// processes hold open are truncated instead, which frees their space.
+func (m Model) removeSelected(truncateOpen bool) tea.Cmd {
+	if m.opts.DryRun {
+		return m.writePlan()
+	}
 	return tea.Cmd(func() tea.Msg {
-		var removed, errors int
-
-		for i, selected := range m.selected {
-			if selected && i < len(m.results) {
-				if err := removeFile(m.results[i].Path); err != nil {
-					errors++
-				} else {
-					removed++
-				}
+		var items, open []scanner.FileItem
+		for _, it := range m.selected.items() {
+			if truncateOpen && len(m.holders[it.Path]) > 0 {
+				open = append(open, it)
+			} else {
+				items = append(items, it)
 			}
 		}
 
-		message := fmt.Sprintf("Removed %d files", removed)
+		var removed, truncated, skipped, replaced, protected, errors int
+		var reclaimed int64
+		r := cleanup.Remover{
+			Strategy:   m.action,
+			Audit:      m.opts.Audit,
+			Force:      m.opts.Force,
+			ArchiveDir: m.opts.ArchiveDir,
+			Protect:    m.opts.Protect,
+		}
+		results, histErr := r.RemoveAll(items, m.opts.History)
+		for _, res := range results {
+			_, isProtected := res.Err.(*cleanup.ProtectedError)
+			_, isReplaced := res.Err.(*cleanup.ReplacedError)
+			switch {
+			case isProtected:
+				protected++
+			case isReplaced:
+				replaced++
+			case res.Skipped:
+				skipped++
+			case res.Err != nil:
+				errors++
+			default:
+				removed++
+				reclaimed += res.Reclaimed
+			}
+		}
+		t := cleanup.Remover{Strategy: cleanup.Truncate, Audit: m.opts.Audit, Protect: m.opts.Protect}
+		truncResults, _ := t.RemoveAll(open, nil)
+		for _, res := range truncResults {
+			_, isReplaced := res.Err.(*cleanup.ReplacedError)
+			switch {
+			case isReplaced:
+				replaced++
+			case res.Err != nil:
+				errors++
+			default:
+				truncated++
+				reclaimed += res.Reclaimed
+			}
+		}
+
+		message := fmt.Sprintf("%s %d files", actionFor(m.action).done, removed)
+		if truncated > 0 {
+			message += fmt.Sprintf(", truncated %d open files", truncated)
+		}
+		if skipped > 0 {
+			message += fmt.Sprintf(", skipped %d open files", skipped)
+		}
+		if protected > 0 {
+			message += fmt.Sprintf(", refused %d protected files", protected)
+		}
+		if replaced > 0 {
+			message += fmt.Sprintf(", skipped %d files replaced since the scan", replaced)
+		}
+		message += fmt.Sprintf(", reclaimed %s", utils.HumanSize(reclaimed))
+		if m.action == cleanup.Trash && removed > 0 {
+			message += " (empty the trash to free the rest)"
+		}
 		if errors > 0 {
 			message += fmt.Sprintf(" (%d errors)", errors)
 		}
+		if histErr != nil {
+			message += fmt.Sprintf("; undo unavailable: %v", histErr)
+		}
 
 		return removeCompleteMsg{
 			removed: removed,
@@ -369,4 +1053,57 @@ func (m Model) removeSelected() tea.Cmd {
 			message: message,
 		}
 	})
-}
\ No newline at end of file
+}
+
+// undoRemoval restores the most recently trashed batch.
+func (m Model) undoRemoval() tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		results, err := m.opts.History.Undo(m.opts.Audit)
+		if err != nil {
+			return removeCompleteMsg{message: fmt.Sprintf("Undo: %v", err)}
+		}
+
+		var restored, errors, gone int
+		var failed []string
+		for _, res := range results {
+			switch {
+			case res.Err == nil:
+				restored++
+			case res.Conflict:
+				errors++
+				failed = append(failed, fmt.Sprintf("%s already exists", res.Path))
+			case res.Gone:
+				errors++
+				gone++
+			default:
+				errors++
+				failed = append(failed, res.Err.Error())
+			}
+		}
+
+		message := fmt.Sprintf("Restored %d of %d files", restored, len(results))
+		if gone > 0 {
+			failed = append(failed, fmt.Sprintf("%d no longer in the trash", gone))
+		}
+		if len(failed) > 0 {
+			message += ": " + strings.Join(failed, "; ")
+		}
+		return removeCompleteMsg{removed: restored, errors: errors, message: message}
+	})
+}
+
+// writePlan records what removeSelected would do in m.opts.PlanPath.
+func (m Model) writePlan() tea.Cmd {
+	return tea.Cmd(func() tea.Msg {
+		plan, errs := cleanup.NewPlan(m.selectedPaths(), m.action)
+		if err := plan.WriteFile(m.opts.PlanPath); err != nil {
+			return planWrittenMsg{message: fmt.Sprintf("Dry run: error writing plan: %v", err)}
+		}
+		message := fmt.Sprintf("Dry run: plan to %s %d files (%s reclaimable) written to %s",
+			plan.Strategy, len(plan.Entries), utils.HumanSize(plan.ReclaimableBytes), m.opts.PlanPath)
+		if len(errs) > 0 {
+			message += fmt.Sprintf(" (%d errors)", len(errs))
+		}
+		return planWrittenMsg{message: message}
+	})
+}
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/natemollica-nm/topn/internal/preview"
+	"github.com/natemollica-nm/topn/internal/scanner"
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+// previewLines is how many lines of content are loaded for the pane; the
+// pane shows as many as fit.
+const previewLines = 64
+
+const timeLayout = "2006-01-02 15:04"
+
+// previewPane is what the preview pane shows: the highlighted row and,
+// once loaded, the description of its file.
+type previewPane struct {
+	key  fileKey
+	item scanner.FileItem
+	data *preview.Preview
+	err  error
+}
+
+type previewMsg struct {
+	key  fileKey
+	data preview.Preview
+	err  error
+}
+
+// loadPreview starts loading the highlighted row into the preview pane,
+// unless it is hidden or already showing that file.
+func (m *Model) loadPreview() tea.Cmd {
+	if !m.showPreview {
+		return nil
+	}
+	i := m.table.Cursor()
+	if i < 0 || i >= len(m.visible) {
+		m.pane = previewPane{}
+		return nil
+	}
+	it := m.visible[i]
+	k := keyOf(it)
+	if k == m.pane.key {
+		return nil
+	}
+	m.pane = previewPane{key: k, item: it}
+	return func() tea.Msg {
+		p, err := preview.Inspect(it.Path, previewLines)
+		return previewMsg{key: k, data: p, err: err}
+	}
+}
+
+// paneWidth is the width of the preview pane, border included.
+func (m Model) paneWidth() int {
+	return max(36, m.width*2/5)
+}
+
+// layout sizes the table to the window, narrowing the path column to make
+// room for the preview pane when it is shown.
+func (m *Model) layout() {
+	if m.width == 0 {
+		return
+	}
+	width := m.width - 4
+	m.pathWidth = defaultPathWidth
+	if m.showPreview {
+		width -= m.paneWidth() + 1
+		cols := columns(m.config, m.sort, 0)
+		fixed := 0
+		for _, c := range cols {
+			fixed += c.Width + 2
+		}
+		// The highlighted row is padded by two more cells; a row wider
+		// than the table wraps.
+		m.pathWidth = max(20, width-fixed-2)
+	}
+	m.table.SetWidth(width)
+	m.table.SetColumns(columns(m.config, m.sort, m.pathWidth))
+}
+
+// previewView renders the preview pane for the highlighted row.
+func (m Model) previewView() string {
+	width := m.paneWidth()
+	inner := width - 6
+	clip := lipgloss.NewStyle().MaxWidth(inner)
+	height := max(8, m.height-14)
+
+	var lines []string
+	add := func(s string) {
+		lines = append(lines, clip.Render(s))
+	}
+	field := func(name, value string) {
+		add(HeaderStyle.Render(fmt.Sprintf("%-10s", name)) + " " + value)
+	}
+
+	it := m.pane.item
+	if it.Path == "" {
+		add(InfoStyle.Render("Nothing to preview"))
+	} else {
+		add(SizeStyle.Render(filepath.Base(it.Path)))
+		add("")
+		field("Size", utils.HumanSize(it.Size))
+		field("Allocated", utils.HumanSize(it.Allocated))
+		if it.IsDir {
+			field("Files", fmt.Sprintf("%d", it.Files))
+		} else {
+			field("Owner", m.owners.name(it.Uid))
+		}
+		switch p := m.pane.data; {
+		case m.pane.err != nil:
+			add("")
+			add(ErrorStyle.Render(m.pane.err.Error()))
+		case p == nil:
+			add("")
+			add(InfoStyle.Render("Loading…"))
+		default:
+			field("Mode", p.Mode.String())
+			field("Modified", p.ModTime.Format(timeLayout))
+			field("Accessed", p.AccessTime.Format(timeLayout))
+			field("Type", p.MIME)
+			if p.Kind != "" {
+				field("", p.Kind)
+			}
+			if len(p.Header) > 0 {
+				add("")
+				for _, h := range p.Header {
+					add(InfoStyle.Render(h))
+				}
+			}
+			if len(p.Lines) > 0 {
+				add("")
+				for _, l := range p.Lines {
+					add(FileStyle.Render(l))
+				}
+				if p.More {
+					add(PathStyle.Render("…"))
+				}
+			}
+		}
+	}
+
+	if len(lines) > height {
+		lines = append(lines[:height-1], PathStyle.Render("…"))
+	}
+	return BorderStyle.Copy().Width(width - 2).Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+func TestPreviewPane(t *testing.T) {
+	dir := t.TempDir()
+	var results []scanner.FileItem
+	for _, name := range []string{"notes.txt", "other.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("first line of "+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		it, err := scanner.StatFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, it)
+	}
+
+	var tm tea.Model = NewModel(scanner.Config{}, Options{})
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+	tm, _ = tm.Update(scanCompleteMsg{results: results})
+	m := tm.(Model)
+	if cmd := m.loadPreview(); cmd != nil {
+		t.Fatal("preview loaded while the pane is hidden")
+	}
+
+	m.showPreview = true
+	m.layout()
+	if m.pathWidth >= defaultPathWidth {
+		t.Errorf("path column is %d wide with the pane shown", m.pathWidth)
+	}
+	cmd := m.loadPreview()
+	if cmd == nil {
+		t.Fatal("no preview load for the highlighted row")
+	}
+	if again := m.loadPreview(); again != nil {
+		t.Error("the same row was loaded twice")
+	}
+	tm, _ = m.Update(cmd())
+	view := tm.(Model).previewView()
+	for _, want := range []string{"notes.txt", "text/plain", "first line of notes.txt", "-rw-r--r--"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("preview lacks %q:\n%s", want, view)
+		}
+	}
+
+	// A load that finishes after the cursor moved on is dropped.
+	m = tm.(Model)
+	m.table.SetCursor(1)
+	if m.loadPreview() == nil {
+		t.Fatal("moving the cursor did not load the new row")
+	}
+	tm, _ = m.Update(cmd())
+	if tm.(Model).pane.data != nil {
+		t.Error("stale preview was shown for the new row")
+	}
+}
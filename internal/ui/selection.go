@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"sort"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+// fileKey identifies a result independently of its row. The path alone is
+// not enough: a file replaced under the same name between scans is a
+// different file and must not inherit the old selection.
+type fileKey struct {
+	path string
+	id   scanner.FileID
+}
+
+func keyOf(it scanner.FileItem) fileKey {
+	return fileKey{path: it.Path, id: it.ID}
+}
+
+// selection holds the selected results by identity, so it survives
+// reordering and rescans. Deselected files are deleted, never set to a zero
+// value, so len is always the number of selected files.
+type selection map[fileKey]scanner.FileItem
+
+func (s selection) has(it scanner.FileItem) bool {
+	_, ok := s[keyOf(it)]
+	return ok
+}
+
+func (s selection) toggle(it scanner.FileItem) {
+	k := keyOf(it)
+	if _, ok := s[k]; ok {
+		delete(s, k)
+		return
+	}
+	s[k] = it
+}
+
+// retain drops selected files that are not in results and refreshes the
+// rest, so sizes shown for the selection match the latest scan.
+func (s selection) retain(results []scanner.FileItem) {
+	seen := make(map[fileKey]bool, len(s))
+	for _, it := range results {
+		k := keyOf(it)
+		if _, ok := s[k]; ok {
+			s[k] = it
+			seen[k] = true
+		}
+	}
+	for k := range s {
+		if !seen[k] {
+			delete(s, k)
+		}
+	}
+}
+
+// items returns the selected files largest first, with ties broken by
+// path so the order is stable.
+func (s selection) items() []scanner.FileItem {
+	items := make([]scanner.FileItem, 0, len(s))
+	for _, it := range s {
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Size != items[j].Size {
+			return items[i].Size > items[j].Size
+		}
+		return items[i].Path < items[j].Path
+	})
+	return items
+}
+
+// paths returns the selected paths in lexical order.
+func (s selection) paths() []string {
+	paths := make([]string, 0, len(s))
+	for k := range s {
+		paths = append(paths, k.path)
+	}
+	sort.Strings(paths)
+	return paths
+}
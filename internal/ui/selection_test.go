@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/natemollica-nm/topn/internal/cleanup"
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+func TestSelection(t *testing.T) {
+	a := scanner.FileItem{Path: "/a", Size: 10, ID: scanner.FileID{Dev: 1, Ino: 1}}
+	b := scanner.FileItem{Path: "/b", Size: 30, ID: scanner.FileID{Dev: 1, Ino: 2}}
+	c := scanner.FileItem{Path: "/c", Size: 30, ID: scanner.FileID{Dev: 1, Ino: 3}}
+
+	s := make(selection)
+	s.toggle(a)
+	s.toggle(b)
+	s.toggle(c)
+	s.toggle(a)
+	if len(s) != 2 || s.has(a) {
+		t.Fatalf("after deselecting /a: %v", s.paths())
+	}
+
+	var got []string
+	for _, it := range s.items() {
+		got = append(got, it.Path)
+	}
+	if want := []string{"/b", "/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("items = %v, want %v", got, want)
+	}
+
+	// A rescan reorders results, grows /b and replaces /c with a new inode.
+	b2 := b
+	b2.Size = 50
+	c2 := c
+	c2.ID.Ino = 4
+	s.retain([]scanner.FileItem{c2, a, b2})
+	if !s.has(b2) || s.has(c2) || len(s) != 1 {
+		t.Fatalf("after rescan: %v", s.paths())
+	}
+	if it := s.items()[0]; it.Size != 50 {
+		t.Errorf("selected size = %d, want the rescanned 50", it.Size)
+	}
+}
+
+func TestSelectAll(t *testing.T) {
+	m := NewModel(scanner.Config{}, Options{Protect: cleanup.Protect{"/p"}})
+	m.results = []scanner.FileItem{
+		{Path: "/a", ID: scanner.FileID{Ino: 1}},
+		{Path: "/p", ID: scanner.FileID{Ino: 2}},
+		{Path: "/b", ID: scanner.FileID{Ino: 3}},
+	}
+	m.updateTable()
+
+	// A toggled-off row must not count towards "all selected".
+	m.selected.toggle(m.results[0])
+	m.selected.toggle(m.results[0])
+	m.selected.toggle(m.results[2])
+	m.selectAll()
+	if got := m.selectedPaths(); !reflect.DeepEqual(got, []string{"/a", "/b"}) {
+		t.Fatalf("select all = %v", got)
+	}
+	m.selectAll()
+	if m.hasSelected() {
+		t.Fatalf("second select all left %v", m.selectedPaths())
+	}
+
+	// With a filter, only the rows it shows are toggled.
+	m.selected.toggle(m.results[0])
+	m.filter.SetValue("b")
+	m.updateTable()
+	m.selectAll()
+	if got := m.selectedPaths(); !reflect.DeepEqual(got, []string{"/a", "/b"}) {
+		t.Fatalf("filtered select all = %v", got)
+	}
+	m.selectAll()
+	if got := m.selectedPaths(); !reflect.DeepEqual(got, []string{"/a"}) {
+		t.Fatalf("filtered deselect all = %v", got)
+	}
+	if m.selectedShown() != 0 {
+		t.Errorf("selectedShown = %d, want 0", m.selectedShown())
+	}
+}
+
+func TestConfirmChecksHoldersAsync(t *testing.T) {
+	m := NewModel(scanner.Config{}, Options{})
+	m.state = stateViewing
+	m.selected.toggle(scanner.FileItem{Path: "/nonexistent/a", ID: scanner.FileID{Dev: 1, Ino: 1}})
+
+	cmd := m.confirm(cleanup.Unlink)
+	if cmd == nil || m.state != stateChecking {
+		t.Fatalf("confirm: state %v, want the holders lookup pending", m.state)
+	}
+	stale := holdersMsg{id: m.checkID - 1}
+	if next, _ := m.Update(stale); next.(Model).state != stateChecking {
+		t.Error("a stale lookup opened the confirmation")
+	}
+	next, _ := m.Update(cmd())
+	if m = next.(Model); m.state != stateConfirming {
+		t.Errorf("after the lookup: state %v, want confirming", m.state)
+	}
+}
+
+func TestDryRunDoesNotRescan(t *testing.T) {
+	m := NewModel(scanner.Config{}, Options{DryRun: true, PlanPath: filepath.Join(t.TempDir(), "plan.json")})
+	m.state = stateScanning
+	id := m.scanID
+	next, cmd := m.Update(m.writePlan()())
+	if m = next.(Model); m.state != stateViewing || m.scanID != id || cmd != nil {
+		t.Errorf("after the plan: state %v, scan %d (was %d), cmd %v; want the view without a rescan", m.state, m.scanID, id, cmd)
+	}
+}
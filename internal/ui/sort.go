@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+// sortField is a column the results table can be ordered by.
+type sortField int
+
+const (
+	sortSize sortField = iota
+	sortPath
+	sortExt
+	sortTime
+	sortOwner
+)
+
+// sortKeys maps the sort keybindings to their fields, in column order.
+var sortKeys = map[string]sortField{
+	"1": sortSize,
+	"2": sortPath,
+	"3": sortExt,
+	"4": sortTime,
+	"5": sortOwner,
+}
+
+func (f sortField) String() string {
+	switch f {
+	case sortPath:
+		return "path"
+	case sortExt:
+		return "extension"
+	case sortTime:
+		return "time"
+	case sortOwner:
+		return "owner"
+	}
+	return "size"
+}
+
+// sortOrder is the current ordering of the results table.
+type sortOrder struct {
+	field sortField
+	asc   bool
+}
+
+// next returns the order after the key for f is pressed: the same field
+// again flips the direction, a new field starts largest or newest first
+// for sizes and times and alphabetically otherwise.
+func (o sortOrder) next(f sortField) sortOrder {
+	if o.field == f {
+		return sortOrder{field: f, asc: !o.asc}
+	}
+	return sortOrder{field: f, asc: f != sortSize && f != sortTime}
+}
+
+// arrow is the header indicator for the column sorted by o.
+func (o sortOrder) arrow() string {
+	if o.asc {
+		return " ▲"
+	}
+	return " ▼"
+}
+
+// sortItems orders items by o. Ties fall back to size, largest first, and
+// then path, so the order never depends on the previous one.
+func sortItems(items []scanner.FileItem, o sortOrder, owners ownerNames) {
+	cmp := func(a, b scanner.FileItem) int {
+		switch o.field {
+		case sortPath:
+			return strings.Compare(a.Path, b.Path)
+		case sortExt:
+			return strings.Compare(strings.ToLower(filepath.Ext(a.Path)), strings.ToLower(filepath.Ext(b.Path)))
+		case sortTime:
+			return a.Time.Compare(b.Time)
+		case sortOwner:
+			return strings.Compare(owners.name(a.Uid), owners.name(b.Uid))
+		}
+		return compareSize(a, b)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		c := cmp(items[i], items[j])
+		if !o.asc {
+			c = -c
+		}
+		if c == 0 && o.field != sortSize {
+			c = -compareSize(items[i], items[j])
+		}
+		if c == 0 {
+			c = strings.Compare(items[i].Path, items[j].Path)
+		}
+		return c < 0
+	})
+}
+
+func compareSize(a, b scanner.FileItem) int {
+	switch {
+	case a.Size < b.Size:
+		return -1
+	case a.Size > b.Size:
+		return 1
+	}
+	return 0
+}
+
+// ownerNames caches user names by uid, since the lookup reads the user
+// database every time.
+type ownerNames map[uint32]string
+
+// name returns the user name for uid, or the number when it has none.
+func (o ownerNames) name(uid uint32) string {
+	if n, ok := o[uid]; ok {
+		return n
+	}
+	n := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(n); err == nil {
+		n = u.Username
+	}
+	o[uid] = n
+	return n
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+func TestSortItems(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	items := []scanner.FileItem{
+		{Path: "/b.log", Size: 20, Time: day(3), Uid: 0},
+		{Path: "/a.TXT", Size: 10, Time: day(1), Uid: 1},
+		{Path: "/c.log", Size: 30, Time: day(2), Uid: 0},
+		{Path: "/d", Size: 20, Time: day(2), Uid: 1},
+	}
+	owners := ownerNames{0: "root", 1: "alice"}
+
+	tests := []struct {
+		order sortOrder
+		want  []string
+	}{
+		{sortOrder{}, []string{"/c.log", "/b.log", "/d", "/a.TXT"}},
+		{sortOrder{field: sortSize, asc: true}, []string{"/a.TXT", "/b.log", "/d", "/c.log"}},
+		{sortOrder{field: sortPath, asc: true}, []string{"/a.TXT", "/b.log", "/c.log", "/d"}},
+		{sortOrder{field: sortPath}, []string{"/d", "/c.log", "/b.log", "/a.TXT"}},
+		// No extension sorts first; ties go largest first.
+		{sortOrder{field: sortExt, asc: true}, []string{"/d", "/c.log", "/b.log", "/a.TXT"}},
+		{sortOrder{field: sortTime}, []string{"/b.log", "/c.log", "/d", "/a.TXT"}},
+		{sortOrder{field: sortOwner, asc: true}, []string{"/d", "/a.TXT", "/c.log", "/b.log"}},
+	}
+	for _, tt := range tests {
+		sortItems(items, tt.order, owners)
+		var got []string
+		for _, it := range items {
+			got = append(got, it.Path)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s asc=%v: got %v, want %v", tt.order.field, tt.order.asc, got, tt.want)
+		}
+	}
+}
+
+func TestSortOrderNext(t *testing.T) {
+	o := sortOrder{}.next(sortPath)
+	if o != (sortOrder{field: sortPath, asc: true}) {
+		t.Errorf("new field: %+v", o)
+	}
+	if o = o.next(sortPath); o.asc {
+		t.Errorf("same field did not reverse: %+v", o)
+	}
+	if o = o.next(sortTime); o != (sortOrder{field: sortTime}) {
+		t.Errorf("time should start newest first: %+v", o)
+	}
+}
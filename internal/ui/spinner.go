@@ -2,45 +2,59 @@ package ui
 
 import (
 	"context"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/natemollica-nm/topn/internal/scanner"
 )
 
+// startScanMsg asks the model to launch a new scan. Init cannot record the
+// scan state on its value receiver, so the first scan is started through it.
+type startScanMsg struct{}
+
 type scanProgressMsg struct {
-	progress float64
-	current  string
+	id       int
+	progress scanner.Progress
+	ch       <-chan scanner.Progress
 }
 
-func (m Model) startScanWithProgress() tea.Cmd {
-	return tea.Cmd(func() tea.Msg {
-		ctx, cancel := context.WithCancel(context.Background())
+// startScan launches a scan in the background and returns the commands that
+// deliver its progress snapshots and final results.
+func (m *Model) startScan() tea.Cmd {
+	m.stopScan()
+	m.scanID++
+	m.scanProgress = scanner.Progress{}
+	id := m.scanID
+	config := m.config
+	ch := make(chan scanner.Progress, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	run := func() tea.Msg {
 		defer cancel()
+		s := scanner.New(config)
+		results, stats := s.ScanWithContext(ctx, ch)
+		return scanCompleteMsg{id: id, results: results, stats: stats}
+	}
+	return tea.Batch(run, waitForProgress(id, ch), m.spinner.Tick)
+}
 
-		// Start progress updates
-		go func() {
-			ticker := time.NewTicker(100 * time.Millisecond)
-			defer ticker.Stop()
-			
-			progress := 0.0
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case <-ticker.C:
-					progress += 0.02
-					if progress > 0.9 {
-						progress = 0.1
-					}
-					// Send progress update (this won't work in real implementation)
-					// In practice, you'd need a more sophisticated progress tracking
-				}
-			}
-		}()
-
-		s := scanner.New(m.config)
-		results, stats := s.Scan()
-		return scanCompleteMsg{results: results, stats: stats}
-	})
-}
\ No newline at end of file
+// waitForProgress blocks until the next snapshot arrives. It returns nil once
+// the scanner closes the channel, which ends the subscription.
+func waitForProgress(id int, ch <-chan scanner.Progress) tea.Cmd {
+	return func() tea.Msg {
+		p, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return scanProgressMsg{id: id, progress: p, ch: ch}
+	}
+}
+
+// stopScan cancels the in-flight scan, if any. The scan still reports what it
+// found so far through scanCompleteMsg.
+func (m *Model) stopScan() {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
@@ -55,4 +55,8 @@ var (
 	HelpStyle = lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#9CA3AF")).
 		MarginTop(1)
+
+	MatchStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#F59E0B")).
+		Underline(true)
 )
\ No newline at end of file
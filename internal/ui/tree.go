@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/natemollica-nm/topn/internal/scanner"
+	"github.com/natemollica-nm/topn/internal/utils"
+)
+
+// barWidth is the width of the share bar in the tree view.
+const barWidth = 20
+
+// treeRow is one line of the tree view: a subdirectory, one of the largest
+// files, or the rest of the files in the directory.
+type treeRow struct {
+	dir   *scanner.Dir
+	name  string
+	size  int64
+	files int64
+}
+
+// treeDir returns the directory the tree view shows, or nil at the list of
+// roots.
+func (m Model) treeDir() *scanner.Dir {
+	if len(m.treePath) == 0 {
+		return nil
+	}
+	return m.treePath[len(m.treePath)-1]
+}
+
+// treeRows lists the entries of the current directory, subdirectories
+// first, each largest first, along with the size they are a share of.
+func (m Model) treeRows() ([]treeRow, int64) {
+	d := m.treeDir()
+	if d == nil {
+		var rows []treeRow
+		var total int64
+		for _, r := range m.tree {
+			rows = append(rows, treeRow{dir: r, name: r.Path + "/", size: r.Size, files: r.Files})
+			total += r.Size
+		}
+		return rows, total
+	}
+
+	var rows []treeRow
+	for _, c := range d.Dirs {
+		rows = append(rows, treeRow{dir: c, name: filepath.Base(c.Path) + "/", size: c.Size, files: c.Files})
+	}
+	size, files := d.Own()
+	for _, f := range d.Top {
+		rows = append(rows, treeRow{name: filepath.Base(f.Path), size: f.Size, files: 1})
+		size -= f.Size
+		files--
+	}
+	if files > 0 {
+		name := fmt.Sprintf("(%d other files)", files)
+		if len(d.Top) == 0 {
+			name = fmt.Sprintf("(%d files)", files)
+		}
+		rows = append(rows, treeRow{name: name, size: size, files: files})
+	}
+	return rows, d.Size
+}
+
+// openTree enters the tree view. A single root is opened straight away.
+// The first time, the scan is rerun to build the tree, since a scan only
+// keeps every directory's total when asked to; the view opens when it
+// completes.
+func (m *Model) openTree() tea.Cmd {
+	if !m.config.Tree {
+		m.config.Tree = true
+		m.treeOnScan = true
+		m.state = stateScanning
+		m.results = nil
+		m.message = ""
+		return m.startScan()
+	}
+	if len(m.treePath) == 0 && len(m.tree) == 1 {
+		m.treePath = []*scanner.Dir{m.tree[0]}
+		m.treeCursor = 0
+	}
+	m.state = stateTree
+	return nil
+}
+
+// descend enters the directory under the cursor.
+func (m *Model) descend() {
+	rows, _ := m.treeRows()
+	if m.treeCursor >= len(rows) || rows[m.treeCursor].dir == nil {
+		return
+	}
+	m.treePath = append(m.treePath, rows[m.treeCursor].dir)
+	m.treeCursor = 0
+}
+
+// ascend goes up to the parent directory, with the cursor on the one just
+// left. It stops at the root when there is only one.
+func (m *Model) ascend() {
+	if len(m.treePath) == 0 || (len(m.treePath) == 1 && len(m.tree) == 1) {
+		return
+	}
+	left := m.treeDir()
+	m.treePath = m.treePath[:len(m.treePath)-1]
+	m.treeCursor = 0
+	rows, _ := m.treeRows()
+	for i, r := range rows {
+		if r.dir == left {
+			m.treeCursor = i
+			break
+		}
+	}
+}
+
+// restoreTree reopens the directory the tree view was at in a new scan's
+// tree, or the deepest ancestor of it that still exists.
+func (m *Model) restoreTree() {
+	d := m.treeDir()
+	m.treePath = nil
+	m.treeCursor = 0
+	if d == nil {
+		return
+	}
+	sep := string(filepath.Separator)
+	for _, r := range m.tree {
+		if d.Path != r.Path && !strings.HasPrefix(d.Path, strings.TrimSuffix(r.Path, sep)+sep) {
+			continue
+		}
+		// Trim the path to its deepest directory still in the tree; r
+		// itself always is.
+		path := d.Path
+		for r.Find(path) == nil {
+			path = filepath.Dir(path)
+		}
+		var up []string
+		for p := path; p != r.Path; p = filepath.Dir(p) {
+			up = append(up, p)
+		}
+		m.treePath = append(m.treePath, r)
+		for i := len(up) - 1; i >= 0; i-- {
+			m.treePath = append(m.treePath, r.Find(up[i]))
+		}
+		return
+	}
+}
+
+// treeView shows the current directory of the tree with each entry's
+// share of it.
+func (m Model) treeView() string {
+	var b strings.Builder
+	b.WriteString(TitleStyle.Render("🌳 TopN - Directory Tree"))
+	b.WriteString("\n\n")
+
+	if len(m.tree) == 0 {
+		b.WriteString(InfoStyle.Render("No directory tree for this scan"))
+		b.WriteString("\n\n")
+		b.WriteString(HelpStyle.Render("tab to return to the list"))
+		return b.String()
+	}
+
+	rows, total := m.treeRows()
+	crumbs := "All roots"
+	var files int64
+	if d := m.treeDir(); d != nil {
+		parts := []string{m.treePath[0].Path}
+		for _, p := range m.treePath[1:] {
+			parts = append(parts, filepath.Base(p.Path))
+		}
+		crumbs = strings.Join(parts, " › ")
+		files = d.Files
+	} else {
+		for _, r := range rows {
+			files += r.files
+		}
+	}
+	b.WriteString(HeaderStyle.Render(truncatePath(crumbs, m.width-4)))
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("%s in %s files\n\n",
+		SizeStyle.Render(utils.HumanSize(total)),
+		InfoStyle.Render(fmt.Sprintf("%d", files))))
+
+	if m.stats.Partial {
+		b.WriteString(WarningStyle.Render("⚠ Partial results: sizes only cover what was scanned"))
+		b.WriteString("\n\n")
+	}
+
+	height := m.height - 10
+	if height < 5 {
+		height = 15
+	}
+	start := 0
+	if m.treeCursor >= height {
+		start = m.treeCursor - height + 1
+	}
+	for i := start; i < len(rows) && i < start+height; i++ {
+		r := rows[i]
+		share := 0.0
+		if total > 0 {
+			share = float64(r.size) / float64(total)
+		}
+		name := FileStyle.Render(r.name)
+		switch {
+		case r.dir != nil:
+			name = InfoStyle.Render(r.name)
+		case strings.HasPrefix(r.name, "("):
+			name = PathStyle.Render(r.name)
+		}
+		line := fmt.Sprintf("%s %5.1f%% %s %s",
+			SizeStyle.Render(fmt.Sprintf("%9s", utils.HumanSize(r.size))),
+			share*100,
+			ProgressStyle.Render(bar(share, barWidth)),
+			name,
+		)
+		cursor := "  "
+		if i == m.treeCursor {
+			cursor = "▶ "
+		}
+		line = cursor + line
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	if len(rows) == 0 {
+		b.WriteString(InfoStyle.Render("Empty"))
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n")
+	b.WriteString(HelpStyle.Render("↑/↓ to move • enter/→ to open • backspace/← to go up • tab for the list • q to quit"))
+	return b.String()
+}
+
+// bar draws share as a bar of width cells.
+func bar(share float64, width int) string {
+	n := int(share*float64(width) + 0.5)
+	if n > width {
+		n = width
+	}
+	return "[" + strings.Repeat("█", n) + strings.Repeat("░", width-n) + "]"
+}
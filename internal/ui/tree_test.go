@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/natemollica-nm/topn/internal/scanner"
+)
+
+// testTree builds /r with a large subdirectory /r/a holding /r/a/b, a
+// smaller /r/c and 10 bytes of files directly in /r.
+func testTree() *scanner.Dir {
+	b := &scanner.Dir{Path: "/r/a/b", Size: 60, Files: 2}
+	a := &scanner.Dir{Path: "/r/a", Size: 100, Files: 3, Dirs: []*scanner.Dir{b},
+		Top: []scanner.FileItem{{Path: "/r/a/big", Size: 40}}}
+	c := &scanner.Dir{Path: "/r/c", Size: 30, Files: 1}
+	return &scanner.Dir{Path: "/r", Size: 140, Files: 6, Dirs: []*scanner.Dir{a, c}}
+}
+
+func TestTreeNavigation(t *testing.T) {
+	m := NewModel(scanner.Config{Tree: true}, Options{})
+	m.tree = []*scanner.Dir{testTree()}
+	m.openTree()
+	if m.state != stateTree || m.treeDir().Path != "/r" {
+		t.Fatalf("a single root should open straight away, at %v", m.treeDir())
+	}
+
+	rows, total := m.treeRows()
+	if total != 140 || len(rows) != 3 || rows[2].name != "(2 files)" || rows[2].size != 10 {
+		t.Fatalf("rows of /r = %+v (total %d)", rows, total)
+	}
+
+	m.descend()
+	if m.treeDir().Path != "/r/a" {
+		t.Fatalf("descend went to %s", m.treeDir().Path)
+	}
+	// /r/a has 40 bytes in 1 file of its own, all of it listed.
+	if rows, _ := m.treeRows(); len(rows) != 2 || rows[1].name != "big" {
+		t.Errorf("rows of /r/a = %+v", rows)
+	}
+	m.treeCursor = 1
+	m.descend()
+	if m.treeDir().Path != "/r/a" {
+		t.Errorf("descending into a file moved to %s", m.treeDir().Path)
+	}
+
+	m.treeCursor = 0
+	m.descend()
+	m.ascend()
+	if m.treeDir().Path != "/r/a" || m.treeCursor != 0 {
+		t.Errorf("ascend from b: at %s row %d", m.treeDir().Path, m.treeCursor)
+	}
+	m.descend()
+
+	// A rescan rebuilds the tree; the view stays at the same path, or the
+	// deepest ancestor that is left.
+	next := testTree()
+	next.Dirs[0].Dirs = nil
+	m.tree = []*scanner.Dir{next}
+	m.restoreTree()
+	if m.treeDir() != next.Dirs[0] || len(m.treePath) != 2 {
+		t.Errorf("restored to %v", m.treeDir())
+	}
+
+	m.ascend()
+	m.ascend()
+	if m.treeDir() != next {
+		t.Errorf("ascend went past the only root")
+	}
+}
+
+func TestTreeBuiltOnFirstOpen(t *testing.T) {
+	m := NewModel(scanner.Config{}, Options{})
+	m.state = stateViewing
+	if cmd := m.openTree(); cmd == nil || m.state != stateScanning || !m.config.Tree {
+		t.Fatalf("first open: state %v, Tree %v; want a rescan building the tree", m.state, m.config.Tree)
+	}
+	m.stopScan()
+
+	var tm tea.Model = m
+	tm, _ = tm.Update(scanCompleteMsg{id: m.scanID, stats: scanner.Stats{Tree: []*scanner.Dir{testTree()}}})
+	m = tm.(Model)
+	if m.state != stateTree || m.treeDir() == nil || m.treeDir().Path != "/r" {
+		t.Errorf("after the scan: state %v at %v, want the tree open at /r", m.state, m.treeDir())
+	}
+}
@@ -1,7 +1,9 @@
 package ui
 
-import "os"
-
-func removeFile(path string) error {
-	return os.Remove(path)
-}
\ No newline at end of file
+// truncatePath shortens path from the left so it fits in width columns.
+func truncatePath(path string, width int) string {
+	if width < 4 || len(path) <= width {
+		return path
+	}
+	return "..." + path[len(path)-width+3:]
+}
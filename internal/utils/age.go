@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ageUnits = map[string]time.Duration{
+	"s":   time.Second,
+	"min": time.Minute,
+	"h":   time.Hour,
+	"d":   24 * time.Hour,
+	"w":   7 * 24 * time.Hour,
+	"mo":  30 * 24 * time.Hour,
+	"y":   365 * 24 * time.Hour,
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseAge turns an age such as "90d", "6mo" or "1y6mo", or an absolute date
+// such as "2024-01-31", into the point in time it refers to relative to now.
+// Units are s, min, h, d, w, mo (30 days) and y (365 days). A bare m is
+// rejected because it could mean either minutes or months.
+func ParseAge(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty age")
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	d, err := parseAgeDuration(strings.ToLower(s))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-d), nil
+}
+
+func parseAgeDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	for s != "" {
+		i := 0
+		for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+			i++
+		}
+		j := i
+		for j < len(s) && s[j] >= 'a' && s[j] <= 'z' {
+			j++
+		}
+		if s[i:j] == "m" {
+			return 0, fmt.Errorf("ambiguous age unit m in %q: use min for minutes or mo for months", s)
+		}
+		val, err := strconv.ParseFloat(s[:i], 64)
+		unit, ok := ageUnits[s[i:j]]
+		if i == 0 || err != nil || !ok {
+			return 0, fmt.Errorf("invalid age: %q (want e.g. 90d, 6mo or 2024-01-31)", s)
+		}
+		total += time.Duration(val * float64(unit))
+		s = s[j:]
+	}
+	return total, nil
+}
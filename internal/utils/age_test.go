@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		input    string
+		expected time.Time
+		hasError bool
+	}{
+		{"90d", now.Add(-90 * day), false},
+		{"6mo", now.Add(-180 * day), false},
+		{"1y6mo", now.Add(-(365 + 180) * day), false},
+		{"2w", now.Add(-14 * day), false},
+		{"12h", now.Add(-12 * time.Hour), false},
+		{"30min", now.Add(-30 * time.Minute), false},
+		{"2024-01-31", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{"", time.Time{}, true},
+		{"90", time.Time{}, true},
+		{"10x", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.input, now)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("ParseAge(%q) expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAge(%q) error: %v", tt.input, err)
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("ParseAge(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseAgeBareM(t *testing.T) {
+	_, err := ParseAge("6m", time.Now())
+	if err == nil || !strings.Contains(err.Error(), "min") || !strings.Contains(err.Error(), "mo") {
+		t.Errorf("ParseAge(\"6m\") error = %v, want one suggesting min or mo", err)
+	}
+}